@@ -0,0 +1,78 @@
+package timeshift
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+var (
+	aliasMutex sync.RWMutex
+	aliases    = map[string]string{}
+
+	aliasNameRE = regexp.MustCompile(`^\w+$`)
+	aliasRefRE  = regexp.MustCompile(`@(\w*)`)
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// RegisterAlias -- registers the pattern that can be used in other patterns as @name. The pattern is validated by New
+// and may use previously registered aliases, they are expanded at the registration time. A repeated registration replaces the pattern
+func RegisterAlias(name string, pattern string) (err error) {
+	if !aliasNameRE.MatchString(name) {
+		err = fmt.Errorf(`illegal alias name "%s"`, name)
+		return
+	}
+
+	pattern, err = expandAliases(strings.TrimSpace(pattern))
+	if err != nil {
+		return
+	}
+
+	_, err = New(pattern, false)
+	if err != nil {
+		return
+	}
+
+	aliasMutex.Lock()
+	aliases[name] = pattern
+	aliasMutex.Unlock()
+
+	return
+}
+
+// expandAliases -- replaces @name by the registered patterns
+func expandAliases(pattern string) (result string, err error) {
+	if strings.IndexByte(pattern, '@') < 0 {
+		result = pattern
+		return
+	}
+
+	aliasMutex.RLock()
+	defer aliasMutex.RUnlock()
+
+	result = aliasRefRE.ReplaceAllStringFunc(pattern,
+		func(ref string) string {
+			p, exists := aliases[ref[1:]]
+			if !exists {
+				if err == nil {
+					err = fmt.Errorf(`unknown alias "%s" in "%s"`, ref, pattern)
+				}
+				return ref
+			}
+			return " " + p + " "
+		},
+	)
+
+	if err != nil {
+		return
+	}
+
+	result = strings.TrimSpace(result)
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
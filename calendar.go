@@ -0,0 +1,117 @@
+package timeshift
+
+import (
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// DaysInMonth -- the number of days in the month of the proleptic Gregorian calendar, the month is normalized (13 is January of the next year)
+func DaysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// week1Start -- the Sunday that starts the first week of the year of t by the rule (not for FirstWeekFromJan1), the clock of t is kept
+func week1Start(t time.Time, rule FirstWeekRule) time.Time {
+	jan1 := t.AddDate(0, 0, -t.YearDay()+1)
+	wd := int(jan1.Weekday())
+
+	shift := 0
+	switch rule {
+	case FirstWeekFull:
+		shift = (7 - wd) % 7 // the first Sunday
+	case FirstWeekContainsThursday:
+		shift = (int(time.Thursday)-wd+7)%7 - int(time.Thursday) // the Sunday before the first Thursday
+	}
+
+	return jan1.AddDate(0, 0, shift)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// StartOfNextDay -- 00:00 of the next day in the location of t
+func StartOfNextDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+}
+
+// StartOfNextWeek -- 00:00 of the next weekStart day after the day of t in the location of t, a week later if t is on weekStart
+func StartOfNextWeek(t time.Time, weekStart time.Weekday) time.Time {
+	n := (int(weekStart) - int(t.Weekday()) + 7) % 7
+	if n == 0 {
+		n = 7
+	}
+
+	year, month, day := t.Date()
+	return time.Date(year, month, day+n, 0, 0, 0, 0, t.Location())
+}
+
+// StartOfNextMonth -- 00:00 of the 1st of the next month in the location of t
+func StartOfNextMonth(t time.Time) time.Time {
+	year, month, _ := t.Date()
+	return time.Date(year, month+1, 1, 0, 0, 0, 0, t.Location())
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func isWeekend(t time.Time) bool {
+	wd := t.Weekday()
+	return wd == time.Saturday || wd == time.Sunday
+}
+
+// BusinessDaysBetween -- the number of business days (Mon-Fri without the holidays) from the date of from to the date of to
+// (not including it) in the location of from. holidays may be nil. It is negative if to is before from
+func BusinessDaysBetween(from time.Time, to time.Time, holidays func(time.Time) bool) (n int) {
+	loc := from.Location() // before the swap
+
+	sign := 1
+	if to.Before(from) {
+		from, to = to, from
+		sign = -1
+	}
+
+	year, month, day := from.In(loc).Date()
+	d := time.Date(year, month, day, 0, 0, 0, 0, loc)
+
+	year, month, day = to.In(loc).Date()
+	end := time.Date(year, month, day, 0, 0, 0, 0, loc)
+
+	for ; d.Before(end); d = d.AddDate(0, 0, 1) {
+		if !isWeekend(d) && (holidays == nil || !holidays(d)) {
+			n++
+		}
+	}
+
+	n *= sign
+	return
+}
+
+// adjustBusinessDay -- moves the weekend day to the business day by the convention
+func adjustBusinessDay(t time.Time, conv BusinessDayConvention) time.Time {
+	move := func(days int) time.Time {
+		r := t
+		for isWeekend(r) {
+			r = r.AddDate(0, 0, days)
+		}
+		return r
+	}
+
+	switch conv {
+	case BusinessDayFollowing:
+		return move(1)
+
+	case BusinessDayPreceding:
+		return move(-1)
+
+	case BusinessDayModifiedFollowing:
+		r := move(1)
+		if r.Month() != t.Month() {
+			r = move(-1)
+		}
+		return r
+	}
+
+	return t
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,182 @@
+package timeshift
+
+import (
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// CancelsWith -- true if both shifts are relative only and b negates a field-by-field. The shifts with the AndThen stages
+// never cancel. Remember that calendar normalization can break it (M+1 M-1 on Jan 31 gives Mar 3)
+func (a *TimeShift) CancelsWith(b *TimeShift) bool {
+	if a == nil {
+		a = &TimeShift{empty: true}
+	}
+	if b == nil {
+		b = &TimeShift{empty: true}
+	}
+
+	if a.then != nil || b.then != nil {
+		return false
+	}
+
+	if a.weekday.active || b.weekday.active {
+		return false // weekday is always absolute
+	}
+
+	pa := a.parts()
+	pb := b.parts()
+
+	for i, x := range pa {
+		y := pb[i]
+
+		if x.active != y.active {
+			return false
+		}
+
+		if !x.active {
+			continue
+		}
+
+		if x.absolute || y.absolute || x.fromBegin || x.fromEnd || y.fromBegin || y.fromEnd {
+			return false
+		}
+
+		if x.val != -y.val {
+			return false
+		}
+	}
+
+	return true
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Compare -- the order of the patterns of shifts for sorting: -1 if a < b, 0 if they are equal, +1 if a > b.
+// The parts are compared in the canonical order, the inactive part is less than the active one, so the empty shift is the first.
+// Then the AndThen stages are compared the same way, the shift without them is less. Options are not compared,
+// so the shifts that differ in the options only are equal but may give different results
+func Compare(a *TimeShift, b *TimeShift) int {
+	if a.isEmpty() {
+		a = &TimeShift{empty: true}
+	}
+	if b.isEmpty() {
+		b = &TimeShift{empty: true}
+	}
+
+	b2i := func(v bool) int {
+		if v {
+			return 1
+		}
+		return 0
+	}
+
+	keys := func(df *partDef) []int {
+		if !df.active {
+			return []int{0}
+		}
+		return []int{1, b2i(df.absolute), b2i(df.fromBegin), b2i(df.fromEnd), b2i(df.nearest), df.val, int(df.set)}
+	}
+
+	pa := a.parts()
+	pb := b.parts()
+
+	for i := range pa {
+		ka := keys(pa[i])
+		kb := keys(pb[i])
+
+		for j := 0; j < len(ka) && j < len(kb); j++ {
+			switch {
+			case ka[j] < kb[j]:
+				return -1
+			case ka[j] > kb[j]:
+				return 1
+			}
+		}
+	}
+
+	switch {
+	case a.then == nil && b.then == nil:
+		return 0
+	case a.then == nil:
+		return -1
+	case b.then == nil:
+		return 1
+	}
+
+	return Compare(a.then, b.then)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// PartDiff -- the difference of one part between two patterns
+	PartDiff struct {
+		Letter byte
+		Kind   DiffKind
+		Before string // the part in the first pattern like "D$3", empty if it is added
+		After  string // the part in the second pattern, empty if it is removed
+	}
+
+	// DiffKind --
+	DiffKind int
+)
+
+const (
+	// PartAdded -- the part is in the second pattern only
+	PartAdded DiffKind = iota
+	// PartRemoved -- the part is in the first pattern only
+	PartRemoved
+	// PartChanged -- the part is in both patterns with different options, sign or value
+	PartChanged
+)
+
+// DiffPatterns -- the parts that differ in the patterns, in the canonical order. The patterns are parsed by New without the cache
+func DiffPatterns(a string, b string) (diff []PartDiff, err error) {
+	tsA, err := New(a, false)
+	if err != nil {
+		return
+	}
+
+	tsB, err := New(b, false)
+	if err != nil {
+		return
+	}
+
+	render := func(df *partDef, name byte) string {
+		if !df.active {
+			return ""
+		}
+
+		var sb strings.Builder
+		df.format(&sb, name)
+		return sb.String()
+	}
+
+	pb := tsB.parts()
+
+	for i, x := range tsA.parts() {
+		name := tokenOrder[i]
+		before := render(x, name)
+		after := render(pb[i], name)
+
+		if before == after {
+			continue
+		}
+
+		d := PartDiff{Letter: name, Kind: PartChanged, Before: before, After: after}
+
+		switch {
+		case before == "":
+			d.Kind = PartAdded
+		case after == "":
+			d.Kind = PartRemoved
+		}
+
+		diff = append(diff, d)
+	}
+
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
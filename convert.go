@@ -0,0 +1,168 @@
+package timeshift
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+var (
+	mapKeys = []struct {
+		key  string
+		name byte
+	}{
+		{"year", 'Y'},
+		{"month", 'M'},
+		{"day", 'D'},
+		{"week", 'W'},
+		{"weeks", 'K'},
+		{"weekday", 'w'},
+		{"isoweekday", 'd'},
+		{"hour", 'h'},
+		{"minute", 'm'},
+		{"second", 's'},
+		{"daysecond", 'S'},
+		{"milli", 'l'},
+		{"micro", 'u'},
+		{"nano", 'n'},
+	}
+
+	mapValueRE = regexp.MustCompile(`^[\^\$!]?[+-]?\d+(?:,\d+)*$`)
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewFromMap -- the map like {"year": "+1", "month": "+2", "day": "$3"}, keys are year, month, day, week, weeks, weekday, isoweekday, hour, minute, second, daysecond, milli, micro, nano.
+// The values are validated by New
+func NewFromMap(m map[string]string, cached bool) (ts *TimeShift, err error) {
+	known := make(map[string]bool, len(mapKeys))
+	for _, k := range mapKeys {
+		known[k.key] = true
+	}
+
+	var unknown []string
+	for k := range m {
+		if !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+
+	if len(unknown) != 0 {
+		sort.Strings(unknown)
+		err = fmt.Errorf(`unknown keys "%s"`, strings.Join(unknown, `", "`))
+		return
+	}
+
+	parts := make([]string, 0, len(m))
+
+	for _, k := range mapKeys {
+		v, exists := m[k.key]
+		if !exists {
+			continue
+		}
+
+		v = strings.TrimSpace(v)
+		if !mapValueRE.MatchString(v) {
+			err = fmt.Errorf(`illegal value "%s" for the "%s"`, v, k.key)
+			return
+		}
+
+		parts = append(parts, string(k.name)+v)
+	}
+
+	return New(strings.Join(parts, " "), cached)
+}
+
+// NewFromGoDuration -- the relative shift from the time.ParseDuration string, "1h30m" is "h+1 m+30", "-1.5s" is "s-1 l-500".
+// The zero duration gives the empty shift
+func NewFromGoDuration(s string, cached bool) (ts *TimeShift, err error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return
+	}
+
+	sign := byte('+')
+	v := uint64(d)
+	if d < 0 {
+		sign = '-'
+		v = uint64(-d) // the minimal duration is converted correctly too
+	}
+
+	parts := make([]string, 0, 6)
+
+	for _, u := range []struct {
+		name byte
+		unit time.Duration
+	}{
+		{'h', time.Hour},
+		{'m', time.Minute},
+		{'s', time.Second},
+		{'l', time.Millisecond},
+		{'u', time.Microsecond},
+		{'n', time.Nanosecond},
+	} {
+		n := v / uint64(u.unit)
+		v %= uint64(u.unit)
+
+		if n != 0 {
+			parts = append(parts, fmt.Sprintf("%c%c%d", u.name, sign, n))
+		}
+	}
+
+	return New(strings.Join(parts, " "), cached)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Snapshot -- the absolute shift that sets all the date and time parts to the wall clock of t, so Exec of it gives t
+// in the location of the source (t itself for the source in the same location). String of the snapshot
+// can be parsed by New for the years 0-9999
+func Snapshot(t time.Time) *TimeShift {
+	year, month, day := t.Date()
+	hour, minute, second := t.Clock()
+	nsec := t.Nanosecond()
+
+	abs := func(v int) partDef {
+		return partDef{active: true, val: v, absolute: true}
+	}
+
+	ts := &TimeShift{
+		year:   abs(year),
+		month:  abs(int(month)),
+		day:    abs(day),
+		hour:   abs(hour),
+		minute: abs(minute),
+		second: abs(second),
+		milli:  abs(nsec / int(time.Millisecond)),
+		micro:  abs(nsec / int(time.Microsecond) % 1000),
+		nano:   abs(nsec % 1000),
+	}
+
+	return ts
+}
+
+// DiffTo -- Snapshot of to in the location of from with the parts down to the finest one only (one of Y, M, D, h, m, s, l, u, n),
+// so Exec of it on from gives to with the finer parts of from: DiffTo(from, to, 'D') sets Y, M and D only.
+// Nil (the empty shift) for the other letters
+func DiffTo(from time.Time, to time.Time, finest byte) *TimeShift {
+	const units = "YMDhmslun"
+
+	n := strings.IndexByte(units, finest)
+	if n < 0 {
+		return nil
+	}
+
+	ts := Snapshot(to.In(from.Location()))
+
+	for _, name := range []byte(units[n+1:]) {
+		ts.parts()[strings.IndexByte(tokenOrder, name)].active = false
+	}
+
+	return ts
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
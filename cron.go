@@ -0,0 +1,148 @@
+package timeshift
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Cron -- the 5-field cron expression ("minute hour day-of-month month day-of-week") with the same matching time, see Matches.
+// The missing parts are "*", the list of weekdays is the list in the day-of-week field, s, l, u and n must be missing or 0.
+// False if the shift can't be represented: the empty shift, relative parts, Y, W, d, S, D$, D!, w with D, the values out of
+// the cron ranges, the AndThen stages, ClampDay and BusinessDayConvention with D
+func (ts *TimeShift) Cron() (expr string, ok bool) {
+	if ts.isEmpty() || ts.then != nil || ts.year.active || ts.week.active || ts.isoWd.active || ts.daySec.active {
+		return
+	}
+
+	for _, df := range ts.parts() {
+		if df.active && (!df.absolute || df.fromBegin || df.fromEnd || df.nearest) {
+			return
+		}
+	}
+
+	for _, df := range []*partDef{&ts.second, &ts.milli, &ts.micro, &ts.nano} {
+		if df.active && df.val != 0 {
+			return
+		}
+	}
+
+	opts := ts.opts()
+	if ts.day.active && (ts.weekday.active || opts.ClampDay || opts.BusinessDayConvention != BusinessDayNone) {
+		return
+	}
+
+	field := func(df *partDef, from int, to int) (string, bool) {
+		if !df.active {
+			return "*", true
+		}
+
+		if df.set != 0 {
+			list := make([]string, 0, 7)
+			for wd := 0; wd < 7; wd++ {
+				if df.set&(1<<uint(wd)) != 0 {
+					list = append(list, strconv.Itoa(wd))
+				}
+			}
+			return strings.Join(list, ","), true
+		}
+
+		return strconv.Itoa(df.val), df.val >= from && df.val <= to
+	}
+
+	fields := make([]string, 0, 5)
+
+	for _, f := range []struct {
+		df       *partDef
+		from, to int
+	}{
+		{&ts.minute, 0, 59},
+		{&ts.hour, 0, 23},
+		{&ts.day, 1, 31},
+		{&ts.month, 1, 12},
+		{&ts.weekday, 0, 6},
+	} {
+		s, valid := field(f.df, f.from, f.to)
+		if !valid {
+			return
+		}
+		fields = append(fields, s)
+	}
+
+	expr = strings.Join(fields, " ")
+	ok = true
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewFromCron -- the absolute shift from the 5-field cron expression ("minute hour day-of-month month day-of-week"),
+// the fields are "*" or a single number, day-of-week 7 is Sunday. The seconds and the sub-seconds are set to 0,
+// so "30 9 * * 1" is "w1 h9 m30 s0 l0 u0 n0". Names, lists, ranges and steps are not supported,
+// the day-of-month with the day-of-week is an error (cron matches any of them, the shift can't)
+func NewFromCron(expr string, cached bool) (ts *TimeShift, err error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		err = fmt.Errorf(`%d fields in the cron expression "%s", expected 5`, len(fields), expr)
+		return
+	}
+
+	defs := []struct {
+		name     string
+		letter   byte
+		from, to int
+	}{
+		{"minute", 'm', 0, 59},
+		{"hour", 'h', 0, 23},
+		{"day-of-month", 'D', 1, 31},
+		{"month", 'M', 1, 12},
+		{"day-of-week", 'w', 0, 7},
+	}
+
+	parts := make(map[byte]string, len(defs))
+
+	for i, f := range fields {
+		d := defs[i]
+
+		if f == "*" {
+			continue
+		}
+
+		if strings.ContainsAny(f, ",-/") {
+			err = fmt.Errorf(`unsupported %s "%s" in the cron expression "%s", lists, ranges and steps are not supported`, d.name, f, expr)
+			return
+		}
+
+		// digits only, Atoi accepts the sign but cron has no signed numbers
+		v, e := strconv.Atoi(f)
+		if e != nil || strings.Trim(f, "0123456789") != "" || v < d.from || v > d.to {
+			err = fmt.Errorf(`illegal %s "%s" in the cron expression "%s", expected "*" or %d-%d`, d.name, f, expr, d.from, d.to)
+			return
+		}
+
+		if d.letter == 'w' {
+			v %= 7 // 7 is Sunday too
+		}
+
+		parts[d.letter] = string(d.letter) + strconv.Itoa(v)
+	}
+
+	if parts['D'] != "" && parts['w'] != "" {
+		err = fmt.Errorf(`day-of-month with day-of-week is not supported in the cron expression "%s"`, expr)
+		return
+	}
+
+	list := make([]string, 0, 9)
+	for _, letter := range []byte("MDwhm") {
+		if p := parts[letter]; p != "" {
+			list = append(list, p)
+		}
+	}
+	list = append(list, "s0", "l0", "u0", "n0")
+
+	return New(strings.Join(list, " "), cached)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
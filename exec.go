@@ -0,0 +1,428 @@
+package timeshift
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ExecNow -- Exec(time.Now()), Options.Clock is used instead of time.Now if it is set
+func (ts *TimeShift) ExecNow() time.Time {
+	return ts.Exec(ts.now())
+}
+
+// ExecNowUTC -- Exec(time.Now().UTC()), Options.Clock is used instead of time.Now if it is set
+func (ts *TimeShift) ExecNowUTC() time.Time {
+	return ts.Exec(ts.now().UTC())
+}
+
+func (ts *TimeShift) now() time.Time {
+	if clock := ts.opts().Clock; clock != nil {
+		return clock()
+	}
+
+	return time.Now()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ExecFormat -- Exec with formatting by the layout. The empty layout is the default, time.RFC3339Nano: there are no overloads
+// in Go, so ExecFormat(t, "") is the RFC3339Nano variant
+func (ts *TimeShift) ExecFormat(t time.Time, layout string) string {
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+
+	return ts.Exec(t).Format(layout)
+}
+
+// the layout of misc.Time2JSONtz, the form used in the tests and the README examples
+const jsonTZLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// ExecJSONtz -- Exec formatted as misc.Time2JSONtz does (2021-02-03T06:20:30.000Z)
+func (ts *TimeShift) ExecJSONtz(t time.Time) string {
+	return ts.Exec(t).Format(jsonTZLayout)
+}
+
+// ExecDelta -- Exec and the duration between the source and the result. For the calendar parts it depends on the source,
+// so "D+1" may give 23 or 25 hours on the daylight saving time switching
+func (ts *TimeShift) ExecDelta(t time.Time) (result time.Time, delta time.Duration) {
+	result = ts.Exec(t)
+	delta = result.Sub(t)
+	return
+}
+
+// ExecIfChanged -- Exec and false if the result is the same as the source (always for the empty shift)
+func (ts *TimeShift) ExecIfChanged(t time.Time) (result time.Time, changed bool) {
+	if ts.isEmpty() {
+		result = t
+		return
+	}
+
+	result = ts.Exec(t)
+	changed = !result.Equal(t)
+	return
+}
+
+// ExecWhen -- Exec if pred(t) is true, t otherwise
+func (ts *TimeShift) ExecWhen(t time.Time, pred func(time.Time) bool) time.Time {
+	if !pred(t) {
+		return t
+	}
+
+	return ts.Exec(t)
+}
+
+// ExecP -- Exec in place through the pointer, nothing is done for nil
+func (ts *TimeShift) ExecP(t *time.Time) {
+	if t == nil {
+		return
+	}
+
+	*t = ts.Exec(*t)
+}
+
+// ExecNull -- Exec for the valid value, the invalid (NULL) value is returned unchanged
+func (ts *TimeShift) ExecNull(t sql.NullTime) sql.NullTime {
+	if !t.Valid {
+		return t
+	}
+
+	return sql.NullTime{Time: ts.Exec(t.Time), Valid: true}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// MatchesInMonth -- for W^ and W$ shifts returns the resolved date of the month (the shift is applied to the 1st of the month at 00:00).
+// The result is empty if the shift is not month-relative or the n-th occurrence does not exist in the month
+func (ts *TimeShift) MatchesInMonth(year int, month time.Month, loc *time.Location) []time.Time {
+	if ts.isEmpty() || !(ts.week.fromBegin || ts.week.fromEnd) {
+		return nil
+	}
+
+	result := ts.Exec(time.Date(year, month, 1, 0, 0, 0, 0, loc))
+
+	if y, m, _ := result.Date(); y != year || m != month {
+		return nil
+	}
+
+	return []time.Time{result}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ExecRange -- the [start, end) period that contains the Exec result. The period is the unit of the finest active part:
+// Y - year, M - month, D, w and d - day, W and K - week (from Sunday), h - hour, m - minute, s and S - second, l - millisecond, u - microsecond, n - nanosecond.
+// So "M-1" gives the previous month and "D-1" gives yesterday. For the empty shift start and end are equal to t
+func (ts *TimeShift) ExecRange(t time.Time) (start time.Time, end time.Time) {
+	if ts.isEmpty() {
+		return t, t
+	}
+
+	r := ts.Exec(t)
+
+	year, month, day := r.Date()
+	hour, minute, second := r.Clock()
+	nsec := r.Nanosecond()
+	loc := r.Location()
+
+	switch ts.FinestUnit() {
+	case 'n':
+		start = r
+		end = start.Add(time.Nanosecond)
+	case 'u':
+		start = time.Date(year, month, day, hour, minute, second, nsec/1000*1000, loc)
+		end = start.Add(time.Microsecond)
+	case 'l':
+		start = time.Date(year, month, day, hour, minute, second, nsec/1000000*1000000, loc)
+		end = start.Add(time.Millisecond)
+	case 's', 'S':
+		start = time.Date(year, month, day, hour, minute, second, 0, loc)
+		end = time.Date(year, month, day, hour, minute, second+1, 0, loc)
+	case 'm':
+		start = time.Date(year, month, day, hour, minute, 0, 0, loc)
+		end = time.Date(year, month, day, hour, minute+1, 0, 0, loc)
+	case 'h':
+		start = time.Date(year, month, day, hour, 0, 0, 0, loc)
+		end = time.Date(year, month, day, hour+1, 0, 0, 0, loc)
+	case 'w', 'd', 'D':
+		start = time.Date(year, month, day, 0, 0, 0, 0, loc)
+		end = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+	case 'W', 'K':
+		day -= int(r.Weekday())
+		start = time.Date(year, month, day, 0, 0, 0, 0, loc)
+		end = time.Date(year, month, day+7, 0, 0, 0, 0, loc)
+	case 'M':
+		start = time.Date(year, month, 1, 0, 0, 0, 0, loc)
+		end = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+	default:
+		start = time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+		end = time.Date(year+1, 1, 1, 0, 0, 0, 0, loc)
+	}
+
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Split -- the date parts (YMDWKwd) and the time parts (hmsSlun) with the same options, the AndThen stages go to the time part.
+// ok is true if the time part applied to the result of the date part gives the same as Exec. It is false if the time parts
+// may move the date across the day boundary (relative or out of the range values) and the date parts depend on the day
+// they are applied to (W, w, d, D$ without ClampDay, D!, D with BusinessDayConvention), Exec applies them after the time is normalized.
+// It is false with RelativeFirst and AbsoluteDirection too, the date depends on the time with them
+func (ts *TimeShift) Split() (date *TimeShift, timePart *TimeShift, ok bool) {
+	if ts.isEmpty() {
+		return ts, ts, true
+	}
+
+	date = ts.only(true)
+	date.empty = date.PartCount() == 0
+
+	timePart = ts.only(false)
+	timePart.then = ts.then
+	timePart.empty = timePart.PartCount() == 0 && timePart.then == nil
+
+	opts := ts.opts()
+	if opts.RelativeFirst || opts.AbsoluteDirection != AbsoluteSame {
+		return
+	}
+
+	ok = !(date.dependsOnDay() && timePart.movesDate())
+	return
+}
+
+// dependsOnDay -- the result of the date parts depends on the day of the source, not only on its year, month and day shift
+func (ts *TimeShift) dependsOnDay() bool {
+	opts := ts.opts()
+	return ts.week.active || ts.weekday.active || ts.isoWd.active ||
+		(ts.day.active && (ts.day.nearest || (ts.day.fromEnd && !opts.ClampDay) || opts.BusinessDayConvention != BusinessDayNone))
+}
+
+// movesDate -- the time parts may move the date: they are relative or out of their range
+func (ts *TimeShift) movesDate() bool {
+	opts := ts.opts()
+	wrapMS := opts.WrapTimeOfDay || opts.WrapMinuteSecond
+
+	list := []struct {
+		df   *partDef
+		max  int
+		wrap bool
+	}{
+		{df: &ts.hour, max: 23, wrap: opts.WrapTimeOfDay},
+		{df: &ts.minute, max: 59, wrap: wrapMS},
+		{df: &ts.second, max: 59, wrap: wrapMS},
+		{df: &ts.daySec, max: 24*3600 - 1, wrap: opts.WrapTimeOfDay},
+		{df: &ts.milli, max: 999},
+		{df: &ts.micro, max: 999},
+		{df: &ts.nano, max: 999},
+	}
+
+	for _, p := range list {
+		if p.df.active && (!p.df.absolute || (!p.wrap && p.df.val > p.max)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ExecDateOnly -- applies the date parts (YMDWKwd) only, the clock of the result is the same as the source one
+func (ts *TimeShift) ExecDateOnly(t time.Time) time.Time {
+	if ts.isEmpty() {
+		return t
+	}
+
+	year, month, day := ts.only(true).Exec(t).Date()
+	hour, minute, second := t.Clock()
+	return time.Date(year, month, day, hour, minute, second, t.Nanosecond(), t.Location())
+}
+
+// ExecDate -- applies the date parts (YMDWKwd) only to the date. The date is normalized as by time.Date (Feb 30 is Mar 2 or 1)
+func (ts *TimeShift) ExecDate(year int, month time.Month, day int) (int, time.Month, int) {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	if ts.isEmpty() {
+		return t.Date()
+	}
+
+	return ts.only(true).Exec(t).Date()
+}
+
+// ExecTimeOnly -- applies the time parts (hmsSlun) only, the date of the result is the same as the source one
+func (ts *TimeShift) ExecTimeOnly(t time.Time) time.Time {
+	if ts.isEmpty() {
+		return t
+	}
+
+	r := ts.only(false).Exec(t)
+
+	year, month, day := t.Date()
+	hour, minute, second := r.Clock()
+	return time.Date(year, month, day, hour, minute, second, r.Nanosecond(), t.Location())
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ExecMany -- Exec for each element, out[i] corresponds to in[i]
+func (ts *TimeShift) ExecMany(in []time.Time) []time.Time {
+	out := make([]time.Time, len(in))
+
+	for i, t := range in {
+		out[i] = ts.Exec(t)
+	}
+
+	return out
+}
+
+// ExecManyParallel -- ExecMany that processes contiguous chunks of the slice concurrently, out[i] corresponds to in[i]
+func (ts *TimeShift) ExecManyParallel(in []time.Time, workers int) []time.Time {
+	if workers > len(in) {
+		workers = len(in)
+	}
+
+	if workers <= 1 {
+		return ts.ExecMany(in)
+	}
+
+	out := make([]time.Time, len(in))
+	size := (len(in) + workers - 1) / workers
+
+	var wg sync.WaitGroup
+
+	for from := 0; from < len(in); from += size {
+		to := from + size
+		if to > len(in) {
+			to = len(in)
+		}
+
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+
+			for i := from; i < to; i++ {
+				out[i] = ts.Exec(in[i])
+			}
+		}(from, to)
+	}
+
+	wg.Wait()
+	return out
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Ambiguity -- the kind of the wall clock in the location
+type Ambiguity int
+
+const (
+	// AmbiguityNone -- the wall clock exists and is unique
+	AmbiguityNone Ambiguity = iota
+	// AmbiguityGap -- the wall clock does not exist (spring forward), time.Date moved it
+	AmbiguityGap
+	// AmbiguityFold -- the wall clock exists twice (fall back), time.Date took one of them
+	AmbiguityFold
+)
+
+// ExecResolve -- Exec and the kind of the wall clock the shift intended to get in the location of t
+func (ts *TimeShift) ExecResolve(t time.Time) (result time.Time, ambiguity Ambiguity) {
+	result = ts.Exec(t)
+	if ts.isEmpty() {
+		return
+	}
+
+	loc := t.Location()
+
+	// the intended wall clock, there are no transitions in UTC
+	year, month, day := t.Date()
+	hour, minute, second := t.Clock()
+	w := ts.Exec(time.Date(year, month, day, hour, minute, second, t.Nanosecond(), time.UTC))
+
+	offsets := make([]int, 0, 3)
+	for _, d := range []time.Duration{-24 * time.Hour, 0, 24 * time.Hour} {
+		_, offset := result.Add(d).Zone()
+
+		exists := false
+		for _, o := range offsets {
+			if o == offset {
+				exists = true
+				break
+			}
+		}
+
+		if !exists {
+			offsets = append(offsets, offset)
+		}
+	}
+
+	n := 0
+	for _, offset := range offsets {
+		candidate := w.Add(-time.Duration(offset) * time.Second).In(loc)
+
+		y, m, d := candidate.Date()
+		h, mi, s := candidate.Clock()
+		if time.Date(y, m, d, h, mi, s, candidate.Nanosecond(), time.UTC).Equal(w) {
+			n++
+		}
+	}
+
+	switch {
+	case n == 0:
+		ambiguity = AmbiguityGap
+	case n > 1:
+		ambiguity = AmbiguityFold
+	}
+
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Compile -- the function that gives the same result as Exec. The shifts with the relative Y, M, D, h, m, s, l, u, n parts only
+// and without options are compiled to a single time.Date call, the rest use Exec
+func (ts *TimeShift) Compile() func(time.Time) time.Time {
+	if ts.isEmpty() {
+		return func(t time.Time) time.Time {
+			return t
+		}
+	}
+
+	if ts.options != nil || ts.then != nil || ts.week.active || ts.weeks.active || ts.weekday.active || ts.isoWd.active || ts.daySec.active {
+		return ts.Exec
+	}
+
+	var v [7]int // year, month, day, hour, minute, second, nanosecond
+
+	for i, df := range []*partDef{&ts.year, &ts.month, &ts.day, &ts.hour, &ts.minute, &ts.second} {
+		if df.active {
+			if df.absolute {
+				return ts.Exec
+			}
+			v[i] = df.val
+		}
+	}
+
+	for _, p := range []struct {
+		df   *partDef
+		unit time.Duration
+	}{
+		{&ts.milli, time.Millisecond},
+		{&ts.micro, time.Microsecond},
+		{&ts.nano, time.Nanosecond},
+	} {
+		if p.df.active {
+			if p.df.absolute {
+				return ts.Exec
+			}
+			v[6] += p.df.val * int(p.unit)
+		}
+	}
+
+	return func(t time.Time) time.Time {
+		year, month, day := t.Date()
+		hour, minute, second := t.Clock()
+		return time.Date(year+v[0], month+time.Month(v[1]), day+v[2], hour+v[3], minute+v[4], second+v[5], t.Nanosecond()+v[6], t.Location())
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,158 @@
+package timeshift
+
+import (
+	"strconv"
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// String -- the canonical form of the shift: the active parts in the canonical order separated by one space, like "Y+1 M2 D$1"
+func (ts *TimeShift) String() string {
+	if ts.isEmpty() {
+		return ""
+	}
+
+	var b strings.Builder
+
+	for i, df := range ts.parts() {
+		if !df.active {
+			continue
+		}
+
+		if b.Len() != 0 {
+			b.WriteByte(' ')
+		}
+
+		df.format(&b, tokenOrder[i])
+	}
+
+	return b.String()
+}
+
+// format -- writes the part with its option, sign and value
+func (df *partDef) format(b *strings.Builder, name byte) {
+	b.WriteByte(name)
+
+	switch {
+	case df.fromBegin:
+		b.WriteByte('^')
+	case df.fromEnd:
+		b.WriteByte('$')
+	case df.nearest:
+		b.WriteByte('!')
+	}
+
+	v := df.val
+	if !df.absolute {
+		if v < 0 {
+			b.WriteByte('-')
+			v = -v
+		} else {
+			b.WriteByte('+')
+		}
+	}
+
+	if df.set == 0 {
+		b.WriteString(strconv.Itoa(v))
+		return
+	}
+
+	sep := ""
+	for i := 0; i < 7; i++ {
+		if df.set&(1<<uint(i)) != 0 {
+			b.WriteString(sep)
+			b.WriteString(strconv.Itoa(i))
+			sep = ","
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// normalizeSpaces -- the spacing of String: exactly one space between the parts. Spaces inside the parts are kept as is,
+// so the validity of the pattern is not changed
+func normalizeSpaces(pattern string) string {
+	isDigit := func(c byte) bool {
+		return c >= '0' && c <= '9'
+	}
+
+	isSpace := func(c byte) bool {
+		return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+	}
+
+	b := make([]byte, 0, len(pattern)+8)
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		afterDigit := i > 0 && isDigit(pattern[i-1])
+
+		if isSpace(c) {
+			j := i + 1
+			for j < len(pattern) && isSpace(pattern[j]) {
+				j++
+			}
+
+			if afterDigit && j < len(pattern) && !isDigit(pattern[j]) {
+				b = append(b, ' ') // between the parts
+			} else {
+				b = append(b, pattern[i:j]...)
+			}
+
+			i = j - 1
+			continue
+		}
+
+		if afterDigit && !isDigit(c) {
+			b = append(b, ' ') // the next part without a space
+		}
+
+		b = append(b, c)
+	}
+
+	return string(b)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// the typical width of the values in the tokenOrder order for FormatPattern
+var formatWidths = []int{4, 2, 2, 2, 2, 1, 1, 2, 2, 2, 5, 3, 3, 3}
+
+// FormatPattern -- the pattern in the aligned form for the config files: the active parts in the canonical order separated
+// by one space, every part except the last one is padded by spaces to the width of the option or the sign and the typical value
+// (4 digits for Y, 2 for M and so on). So the signs and the values of the patterns with the same parts formatted one under another
+// line up, the longer values shift the rest of the line. The result is parsed by New to the same shift
+func FormatPattern(pattern string) (s string, err error) {
+	ts, err := New(pattern, false)
+	if err != nil {
+		return
+	}
+
+	if ts.isEmpty() {
+		return
+	}
+
+	var b strings.Builder
+
+	for i, df := range ts.parts() {
+		if !df.active {
+			continue
+		}
+
+		if b.Len() != 0 {
+			b.WriteByte(' ')
+		}
+
+		n := b.Len()
+		df.format(&b, tokenOrder[i])
+
+		for w := 2 + formatWidths[i]; b.Len()-n < w; {
+			b.WriteByte(' ')
+		}
+	}
+
+	s = strings.TrimRight(b.String(), " ")
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -2,4 +2,7 @@ module github.com/alrusov/timeshift
 
 go 1.23.1
 
-require github.com/alrusov/misc v1.1.15
+require (
+	github.com/alrusov/misc v1.1.15
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,138 @@
+package timeshift
+
+import (
+	"strings"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// PartCount -- number of active parts
+func (ts *TimeShift) PartCount() (n int) {
+	for _, df := range ts.parts() {
+		if df.active {
+			n++
+		}
+	}
+
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Matches -- true if applying the absolute and anchored parts of the shift does not change t.
+// Relative parts are ignored, so a relative only (or empty) shift matches any time
+func (ts *TimeShift) Matches(t time.Time) bool {
+	if ts.isEmpty() {
+		return true
+	}
+
+	return ts.relativeOnly(false).Exec(t).Equal(t)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ResolvedWeekday -- the weekday Exec(t) lands on and true if W, w or d is active. It is w if it is the only weekday
+// and there are no AndThen stages and AbsoluteDirection, otherwise the weekday of Exec(t)
+func (ts *TimeShift) ResolvedWeekday(t time.Time) (wd time.Weekday, ok bool) {
+	if ts.isEmpty() {
+		return t.Weekday(), false
+	}
+
+	ok = ts.week.active || ts.weekday.active || ts.isoWd.active
+
+	if ts.weekday.active && ts.weekday.set == 0 && ts.weekday.val >= 0 && ts.weekday.val <= 6 &&
+		ts.then == nil && ts.opts().AbsoluteDirection == AbsoluteSame {
+		wd = time.Weekday(ts.weekday.val)
+		return
+	}
+
+	wd = ts.Exec(t).Weekday()
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// the parts from the finest unit to the largest one, the parts of the same unit are together
+const unitOrder = "nulSsmhwdDWKMY"
+
+// FinestUnit -- the letter of the finest active part (n < u < l < s, S < m < h < w, d, D < W, K < M < Y), 0 for the empty shift.
+// Exec does not change the finer units of the source
+func (ts *TimeShift) FinestUnit() byte {
+	if ts.isEmpty() {
+		return 0
+	}
+
+	parts := ts.parts()
+
+	for i := 0; i < len(unitOrder); i++ {
+		if parts[strings.IndexByte(tokenOrder, unitOrder[i])].active {
+			return unitOrder[i]
+		}
+	}
+
+	return 0
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Period -- the approximate recurrence interval of the absolute pattern, it is defined by the coarsest part:
+// 24h for h, m, s, S, l, u, n, 7 days for w and d, the average Gregorian month for D and W^/W$, the average Gregorian year for M and W.
+// False for the empty shift, the shift with relative parts, an absolute Y, the list of weekdays or the AndThen stages
+func (ts *TimeShift) Period() (period time.Duration, ok bool) {
+	if ts.isEmpty() || ts.then != nil || ts.year.active || ts.weekday.set != 0 {
+		return
+	}
+
+	for _, df := range ts.parts() {
+		if df.active && !df.absolute {
+			return
+		}
+	}
+
+	// the coarsest active part
+	parts := ts.parts()
+	unit := byte(0)
+	for i := len(unitOrder) - 1; unit == 0; i-- {
+		if parts[strings.IndexByte(tokenOrder, unitOrder[i])].active {
+			unit = unitOrder[i]
+		}
+	}
+
+	const (
+		day   = 24 * time.Hour
+		year  = 146097 * (day / 400) // the average Gregorian year
+		month = year / 12
+	)
+
+	switch {
+	case unit == 'M' || (unit == 'W' && !ts.week.fromBegin && !ts.week.fromEnd):
+		period = year
+	case unit == 'W' || unit == 'D':
+		period = month
+	case unit == 'w' || unit == 'd':
+		period = 7 * day
+	default:
+		period = day
+	}
+
+	ok = true
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Deterministic -- true if Exec always gives the same result for the same source. Only such shifts are cached by New.
+// All the current parts are deterministic
+func (ts *TimeShift) Deterministic() bool {
+	return true
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// TokenOrder -- the canonical order of parts
+func TokenOrder() []byte {
+	return []byte(tokenOrder)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,89 @@
+package timeshift
+
+import (
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// Options --
+	Options struct {
+		RequireLocation       *time.Location        // ExecChecked returns an error if the source time is in another location
+		ClampWeek             bool                  // W^ and W$ that go out of the month are clamped to the last (first) available week of the month
+		WrapTimeOfDay         bool                  // absolute h, m and s wrap modulo their range without changing the date
+		ClampDay              bool                  // absolute D is clamped to the last day of the month, D$ is counted from the end of the target month and clamped to the first day
+		WeekFromInput         bool                  // absolute W moves the source by whole Sunday based weeks of its year instead of counting the weekday occurrences from Jan 1
+		AllowAnyOrder         bool                  // parts may be in any order, New sorts them into the canonical one
+		FromEndZeroBased      bool                  // D$0 is the last day of the month, D$1 is the day before it
+		PreserveMonthEnd      bool                  // the last day of the month is mapped to the last day of the target month by relative M
+		RelativeFirst         bool                  // relative parts are applied to the source first, then absolute and anchored parts are applied to the result
+		NormalizeCacheKey     bool                  // the cache key is the pattern with the spacing of String, so "Y+1M+2" and "Y+1   M+2" share the same cache entry
+		BusinessDayConvention BusinessDayConvention // the adjustment of the absolute (and $) day that falls on the weekend
+		StrictWeekday         bool                  // w (or d) with the absolute D without W is an error
+		FirstWeekRule         FirstWeekRule         // the first week of the year for the absolute W
+		WrapMinuteSecond      bool                  // absolute m and s wrap modulo 60 without changing the hour
+		Clock                 func() time.Time      // the source of the current time for ExecNow and ExecNowUTC, nil - time.Now
+		AllowMonthSnap        bool                  // M^+n and M^-n shift the month and snap to its begin (the 1st at 00:00) before the finer parts
+		StrictConflicts       bool                  // the parts whose effect is overridden by W with w are an error
+		WeekdayFromEnd        bool                  // negative w values are counted from the end of the week: w-1 is Saturday
+		AbsoluteDirection     AbsoluteDirection     // how the absolute time parts are resolved relative to the source
+		WeekOfMonthRule       WeekOfMonthRule       // the first week of the month for W^
+	}
+
+	// BusinessDayConvention -- how the absolute day that falls on the weekend is moved to the business day (Mon-Fri)
+	BusinessDayConvention int
+
+	// FirstWeekRule -- how the first week of the year is defined for the absolute W
+	FirstWeekRule int
+
+	// AbsoluteDirection -- how the absolute time parts are resolved relative to the source
+	AbsoluteDirection int
+
+	// WeekOfMonthRule -- how the first week of the month is defined for W^
+	WeekOfMonthRule int
+)
+
+const (
+	// BusinessDayNone -- no adjustment
+	BusinessDayNone BusinessDayConvention = iota
+	// BusinessDayFollowing -- forward to the next business day
+	BusinessDayFollowing
+	// BusinessDayPreceding -- back to the previous business day
+	BusinessDayPreceding
+	// BusinessDayModifiedFollowing -- forward to the next business day unless it is in the next month, back otherwise
+	BusinessDayModifiedFollowing
+)
+
+const (
+	// FirstWeekFromJan1 -- Wn is the n-th occurrence of the weekday counting from Jan 1
+	FirstWeekFromJan1 FirstWeekRule = iota
+	// FirstWeekFull -- week 1 is the first full week (from Sunday) of the year
+	FirstWeekFull
+	// FirstWeekContainsThursday -- week 1 is the week (from Sunday) that contains the first Thursday of the year
+	FirstWeekContainsThursday
+)
+
+const (
+	// AbsoluteSame -- the absolute time parts are set within the same day (hour, minute...) as the source
+	AbsoluteSame AbsoluteDirection = iota
+	// AbsoluteForward -- the result is not before the source
+	AbsoluteForward
+	// AbsoluteBackward -- the result is not after the source
+	AbsoluteBackward
+	// AbsoluteNearest -- the result is the closest to the source
+	AbsoluteNearest
+)
+
+const (
+	// WeekOfMonthFirstOccurrence -- W^n is the n-th occurrence of the weekday in the month
+	WeekOfMonthFirstOccurrence WeekOfMonthRule = iota
+	// WeekOfMonthFull -- week 1 is the first full week (from Sunday) of the month
+	WeekOfMonthFull
+)
+
+var (
+	defaultOptions Options
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,54 @@
+package timeshift
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Pipe -- PipeLayout with the RFC3339Nano layout
+func (ts *TimeShift) Pipe(r io.Reader, w io.Writer) error {
+	return ts.PipeLayout(r, w, time.RFC3339Nano)
+}
+
+// PipeLayout -- reads newline-delimited timestamps in the layout from r and writes the shifted ones to w.
+// On the error the lines before the failed one are written
+func (ts *TimeShift) PipeLayout(r io.Reader, w io.Writer, layout string) (err error) {
+	scanner := bufio.NewScanner(r)
+	bw := bufio.NewWriter(w)
+
+	defer func() {
+		if e := bw.Flush(); e != nil && err == nil {
+			err = e
+		}
+	}()
+
+	n := 0
+	for scanner.Scan() {
+		n++
+
+		src := strings.TrimSpace(scanner.Text())
+		t, e := time.Parse(layout, src)
+		if e != nil {
+			err = fmt.Errorf(`line %d: %s`, n, e)
+			return
+		}
+
+		_, err = bw.WriteString(ts.Exec(t).Format(layout) + "\n")
+		if err != nil {
+			return
+		}
+	}
+
+	if e := scanner.Err(); e != nil {
+		err = fmt.Errorf(`line %d: %s`, n+1, e)
+	}
+
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,360 @@
+package timeshift
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// RRULE -- the iCalendar RRULE with the same matching time (see Matches): "W^2 w1 h9 m0 s0" is "FREQ=MONTHLY;BYDAY=2MO;BYHOUR=9;BYMINUTE=0".
+// FREQ is the unit above the coarsest part: MINUTELY for s, HOURLY for m, DAILY for h, WEEKLY for w and d, MONTHLY for D, W^ and W$,
+// YEARLY for M. The zero s, l, u and n are not included (DTSTART is expected at the whole minute), the other values of them are not supported.
+// False if the shift can't be represented: the empty shift, the shift without BY fields (s0, l0...), relative parts, Y,
+// the absolute W, W^ and W$ without w or over 5, S, D!, w with D, the values out of the ranges, the AndThen stages,
+// ClampDay and BusinessDayConvention with D, ClampWeek with W^ and W$, WeekOfMonthFull with W^
+func (ts *TimeShift) RRULE() (rule string, ok bool) {
+	if ts.isEmpty() || ts.then != nil || ts.year.active || ts.daySec.active || ts.day.nearest {
+		return
+	}
+
+	for _, df := range ts.parts() {
+		if df.active && !df.absolute {
+			return
+		}
+	}
+
+	for _, df := range []*partDef{&ts.milli, &ts.micro, &ts.nano} {
+		if df.active && df.val != 0 {
+			return
+		}
+	}
+
+	opts := ts.opts()
+
+	if ts.day.active && (ts.weekday.active || ts.isoWd.active || opts.ClampDay || opts.BusinessDayConvention != BusinessDayNone) {
+		return
+	}
+
+	anchored := ts.week.fromBegin || ts.week.fromEnd
+	if ts.week.active && (!anchored || !ts.weekday.active || ts.week.val > 5 || opts.ClampWeek || (ts.week.fromBegin && opts.WeekOfMonthRule != WeekOfMonthFirstOccurrence)) {
+		return
+	}
+
+	inRange := func(df *partDef, from int, to int) bool {
+		return !df.active || (df.val >= from && df.val <= to)
+	}
+
+	if !inRange(&ts.month, 1, 12) || !inRange(&ts.hour, 0, 23) || !inRange(&ts.minute, 0, 59) || !inRange(&ts.second, 0, 59) {
+		return
+	}
+
+	var b strings.Builder
+
+	b.WriteString("FREQ=")
+	switch {
+	case ts.month.active:
+		b.WriteString("YEARLY")
+	case ts.day.active || ts.week.active:
+		b.WriteString("MONTHLY")
+	case ts.weekday.active || ts.isoWd.active:
+		b.WriteString("WEEKLY")
+	case ts.hour.active:
+		b.WriteString("DAILY")
+	case ts.minute.active:
+		b.WriteString("HOURLY")
+	default:
+		b.WriteString("MINUTELY")
+	}
+
+	fields := 0
+	add := func(name string, v string) {
+		fields++
+		b.WriteByte(';')
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(v)
+	}
+
+	if ts.month.active {
+		add("BYMONTH", strconv.Itoa(ts.month.val))
+	}
+
+	if ts.day.active {
+		v := ts.day.val
+		if ts.day.fromEnd {
+			if opts.FromEndZeroBased {
+				v++
+			}
+			v = -v
+		}
+
+		if v == 0 || v < -31 || v > 31 {
+			return
+		}
+		add("BYMONTHDAY", strconv.Itoa(v))
+	}
+
+	switch {
+	case ts.weekday.set != 0:
+		list := make([]string, 0, 7)
+		for wd := 0; wd < 7; wd++ {
+			if ts.weekday.set&(1<<uint(wd)) != 0 {
+				list = append(list, weekdayAbbr[wd])
+			}
+		}
+		add("BYDAY", strings.Join(list, ","))
+
+	case ts.weekday.active:
+		n := ""
+		switch {
+		case ts.week.fromBegin:
+			n = strconv.Itoa(ts.week.val)
+		case ts.week.fromEnd:
+			n = strconv.Itoa(-ts.week.val)
+		}
+		add("BYDAY", n+weekdayAbbr[ts.weekday.val])
+
+	case ts.isoWd.active:
+		add("BYDAY", weekdayAbbr[ts.isoWd.val%7])
+	}
+
+	if ts.hour.active {
+		add("BYHOUR", strconv.Itoa(ts.hour.val))
+	}
+
+	if ts.minute.active {
+		add("BYMINUTE", strconv.Itoa(ts.minute.val))
+	}
+
+	if ts.second.active && ts.second.val != 0 {
+		add("BYSECOND", strconv.Itoa(ts.second.val))
+	}
+
+	if fields == 0 {
+		return // the bare FREQ matches any time, it is not the same as the shift
+	}
+
+	rule = b.String()
+	ok = true
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// NewFromRRULE -- the absolute shift from the subset of the iCalendar RRULE (the "RRULE:" prefix is allowed): FREQ, INTERVAL=1,
+// BYMONTH, BYMONTHDAY (negative - from the end of the month), BYDAY, BYHOUR, BYMINUTE and BYSECOND with single values.
+// BYDAY is the weekday ("MO"), the n-th weekday of the month for MONTHLY and YEARLY with BYMONTH ("2MO" is "W^2 w1",
+// "-1FR" is "W$1 w5"), the n-th weekday of the year for YEARLY without BYMONTH ("10MO" is "W10 w1") or the list of weekdays ("SA,SU").
+// The missing parts are taken from the source (DTSTART). The other fields, the lists and BYMONTHDAY with BYDAY are errors
+func NewFromRRULE(rrule string, cached bool) (ts *TimeShift, err error) {
+	src := strings.TrimPrefix(strings.TrimSpace(rrule), "RRULE:")
+
+	fields := make(map[string]string, 8)
+
+	for _, f := range strings.Split(src, ";") {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			err = fmt.Errorf(`illegal field "%s" in the RRULE "%s"`, f, rrule)
+			return
+		}
+
+		name := strings.ToUpper(kv[0])
+		if _, exists := fields[name]; exists {
+			err = fmt.Errorf(`duplicate field "%s" in the RRULE "%s"`, name, rrule)
+			return
+		}
+		fields[name] = strings.ToUpper(kv[1])
+	}
+
+	freq := fields["FREQ"]
+	switch freq {
+	case "YEARLY", "MONTHLY", "WEEKLY", "DAILY", "HOURLY", "MINUTELY":
+	case "":
+		err = fmt.Errorf(`FREQ is missing in the RRULE "%s"`, rrule)
+		return
+	default:
+		err = fmt.Errorf(`unsupported FREQ "%s" in the RRULE "%s"`, freq, rrule)
+		return
+	}
+
+	if v, exists := fields["INTERVAL"]; exists && v != "1" {
+		err = fmt.Errorf(`unsupported INTERVAL "%s" in the RRULE "%s", only 1 is supported`, v, rrule)
+		return
+	}
+
+	for name := range fields {
+		switch name {
+		case "FREQ", "INTERVAL", "BYMONTH", "BYMONTHDAY", "BYDAY", "BYHOUR", "BYMINUTE", "BYSECOND":
+		default:
+			err = fmt.Errorf(`unsupported field "%s" in the RRULE "%s"`, name, rrule)
+			return
+		}
+	}
+
+	if fields["BYMONTHDAY"] != "" && fields["BYDAY"] != "" {
+		err = fmt.Errorf(`BYMONTHDAY with BYDAY is not supported in the RRULE "%s"`, rrule)
+		return
+	}
+
+	parts := make([]string, 0, 7)
+
+	number := func(name string, from int, to int) (v int, exists bool, err error) {
+		s, exists := fields[name]
+		if !exists {
+			return
+		}
+
+		v, e := strconv.Atoi(s)
+		if e != nil || v < from || v > to {
+			err = fmt.Errorf(`illegal %s "%s" in the RRULE "%s", a single value %d..%d is expected`, name, s, rrule, from, to)
+		}
+		return
+	}
+
+	month, hasMonth, err := number("BYMONTH", 1, 12)
+	if err != nil {
+		return
+	}
+	if hasMonth {
+		parts = append(parts, "M"+strconv.Itoa(month))
+	}
+
+	day, exists, err := number("BYMONTHDAY", -31, 31)
+	if err != nil {
+		return
+	}
+	if exists {
+		switch {
+		case day == 0:
+			err = fmt.Errorf(`illegal BYMONTHDAY "0" in the RRULE "%s"`, rrule)
+			return
+		case day < 0:
+			parts = append(parts, "D$"+strconv.Itoa(-day))
+		default:
+			parts = append(parts, "D"+strconv.Itoa(day))
+		}
+	}
+
+	if byDay, exists := fields["BYDAY"]; exists {
+		var p string
+		p, err = rruleDay(byDay, freq, hasMonth)
+		if err != nil {
+			err = fmt.Errorf(`illegal BYDAY "%s" in the RRULE "%s": %s`, byDay, rrule, err)
+			return
+		}
+		parts = append(parts, p)
+	}
+
+	for _, f := range []struct {
+		name   string
+		letter string
+		to     int
+	}{
+		{"BYHOUR", "h", 23},
+		{"BYMINUTE", "m", 59},
+		{"BYSECOND", "s", 59},
+	} {
+		v, exists, e := number(f.name, 0, f.to)
+		if e != nil {
+			err = e
+			return
+		}
+		if exists {
+			parts = append(parts, f.letter+strconv.Itoa(v))
+		}
+	}
+
+	if len(parts) == 0 {
+		err = fmt.Errorf(`no BY fields in the RRULE "%s"`, rrule)
+		return
+	}
+
+	return New(strings.Join(parts, " "), cached)
+}
+
+// rruleDay -- the BYDAY value as W and w parts
+func rruleDay(v string, freq string, hasMonth bool) (p string, err error) {
+	wd := func(abbr string) int {
+		for i, a := range weekdayAbbr {
+			if a == abbr {
+				return i
+			}
+		}
+		return -1
+	}
+
+	if strings.IndexByte(v, ',') >= 0 {
+		list := strings.Split(v, ",")
+		nums := make([]int, 0, len(list))
+		for _, abbr := range list {
+			n := wd(abbr)
+			if n < 0 {
+				err = fmt.Errorf(`only the weekdays without numbers are allowed in the list`)
+				return
+			}
+			nums = append(nums, n)
+		}
+		sort.Ints(nums)
+
+		strs := make([]string, len(nums))
+		for i, n := range nums {
+			if i > 0 && n == nums[i-1] {
+				err = fmt.Errorf(`duplicate weekday "%s" in the list`, weekdayAbbr[n])
+				return
+			}
+			strs[i] = strconv.Itoa(n)
+		}
+
+		p = "w" + strings.Join(strs, ",")
+		return
+	}
+
+	if len(v) < 2 {
+		err = fmt.Errorf(`the weekday is expected`)
+		return
+	}
+
+	n := wd(v[len(v)-2:])
+	if n < 0 {
+		err = fmt.Errorf(`unknown weekday "%s", expected one of %s`, v[len(v)-2:], strings.Join(weekdayAbbr, ", "))
+		return
+	}
+
+	p = "w" + strconv.Itoa(n)
+
+	if len(v) == 2 {
+		return
+	}
+
+	ord, e := strconv.Atoi(v[:len(v)-2])
+	switch {
+	case e != nil || ord == 0:
+		err = fmt.Errorf(`illegal number of the weekday`)
+
+	case freq == "MONTHLY" || (freq == "YEARLY" && hasMonth):
+		if ord < -5 || ord > 5 {
+			err = fmt.Errorf(`the number of the weekday in the month must be -5..5`)
+		} else if ord < 0 {
+			p = "W$" + strconv.Itoa(-ord) + " " + p
+		} else {
+			p = "W^" + strconv.Itoa(ord) + " " + p
+		}
+
+	case freq == "YEARLY":
+		if ord < 0 || ord > 53 {
+			err = fmt.Errorf(`the number of the weekday in the year must be 1..53`)
+		} else {
+			p = "W" + strconv.Itoa(ord) + " " + p
+		}
+
+	default:
+		err = fmt.Errorf(`the number of the weekday is allowed for MONTHLY and YEARLY only`)
+	}
+
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,71 @@
+package timeshift
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+var (
+	// RRULE like "2MO" - the second Monday of the month, "-1FR" - the last Friday of the month
+	shorthandRE = regexp.MustCompile(`R(-?)(\d+)([A-Za-z]{2})`)
+
+	// in the Sunday = 0 order
+	weekdayAbbr = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// expandShorthands -- replaces Rnxx (n - number of the week, xx - weekday abbreviation like MO) by "W^n wX" and R-nxx by "W$n wX"
+func expandShorthands(pattern string) (result string, err error) {
+	if strings.IndexByte(pattern, 'R') < 0 {
+		result = pattern
+		return
+	}
+
+	result = shorthandRE.ReplaceAllStringFunc(pattern,
+		func(src string) string {
+			if err != nil {
+				return src
+			}
+
+			m := shorthandRE.FindStringSubmatch(src)
+
+			if n, _ := strconv.Atoi(m[2]); n == 0 {
+				err = fmt.Errorf(`illegal zero count in the "%s"`, src)
+				return src
+			}
+
+			wd := -1
+			for i, abbr := range weekdayAbbr {
+				if abbr == m[3] {
+					wd = i
+					break
+				}
+			}
+			if wd < 0 {
+				err = fmt.Errorf(`illegal weekday "%s" in the "%s", expected one of %s`, m[3], src, strings.Join(weekdayAbbr, ", "))
+				return src
+			}
+
+			anchor := "^"
+			if m[1] == "-" {
+				anchor = "$"
+			}
+
+			return " W" + anchor + m[2] + " w" + strconv.Itoa(wd) + " "
+		},
+	)
+
+	if err != nil {
+		return
+	}
+
+	result = strings.TrimSpace(result)
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
@@ -0,0 +1,139 @@
+package timeshift
+
+import (
+	"fmt"
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// Spec -- the field addressable form of the shift, the parts are in the canonical order
+	Spec struct {
+		Year       SpecPart
+		Month      SpecPart
+		Day        SpecPart
+		Week       SpecPart
+		Weeks      SpecPart
+		Weekday    SpecPart
+		ISOWeekday SpecPart
+		Hour       SpecPart
+		Minute     SpecPart
+		Second     SpecPart
+		DaySecond  SpecPart
+		Milli      SpecPart
+		Micro      SpecPart
+		Nano       SpecPart
+
+		Options *Options // nil - the default options
+	}
+
+	// SpecPart -- the part of the Spec
+	SpecPart struct {
+		Active    bool
+		Value     int   // signed for the relative part, non-negative for the absolute one
+		Relative  bool  // "+" or "-"
+		FromBegin bool  // "^"
+		FromEnd   bool  // "$"
+		Nearest   bool  // "!"
+		Weekdays  []int // for the weekday only: the list of weekdays, Value is ignored if it is not empty
+	}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func (s *Spec) parts() []*SpecPart {
+	// in the tokenOrder order
+	return []*SpecPart{
+		&s.Year, &s.Month, &s.Day, &s.Week, &s.Weeks, &s.Weekday, &s.ISOWeekday,
+		&s.Hour, &s.Minute, &s.Second, &s.DaySecond, &s.Milli, &s.Micro, &s.Nano,
+	}
+}
+
+// Spec -- the Spec of the shift with its options. The AndThen stages are not included
+func (ts *TimeShift) Spec() (s Spec) {
+	if ts == nil {
+		return
+	}
+
+	s.Options = ts.options
+
+	if ts.empty {
+		return
+	}
+
+	sp := s.parts()
+
+	for i, df := range ts.parts() {
+		if !df.active {
+			continue
+		}
+
+		p := sp[i]
+		p.Active = true
+		p.Value = df.val
+		p.Relative = !df.absolute
+		p.FromBegin = df.fromBegin
+		p.FromEnd = df.fromEnd
+		p.Nearest = df.nearest
+
+		for wd := 0; wd < 7 && df.set != 0; wd++ {
+			if df.set&(1<<uint(wd)) != 0 {
+				p.Weekdays = append(p.Weekdays, wd)
+			}
+		}
+	}
+
+	return
+}
+
+// Build -- the uncached shift with the options of the Spec, the parts are validated by NewWithOptions
+func (s Spec) Build() (ts *TimeShift, err error) {
+	var b strings.Builder
+
+	for i, p := range s.parts() {
+		if !p.Active {
+			continue
+		}
+
+		name := tokenOrder[i]
+
+		if !p.Relative && p.Value < 0 {
+			err = fmt.Errorf(`negative absolute value %d for "%c"`, p.Value, name)
+			return
+		}
+
+		df := partDef{
+			active:    true,
+			val:       p.Value,
+			absolute:  !p.Relative,
+			fromBegin: p.FromBegin,
+			fromEnd:   p.FromEnd,
+			nearest:   p.Nearest,
+		}
+
+		for _, wd := range p.Weekdays {
+			if name != 'w' || wd < 0 || wd > 6 {
+				err = fmt.Errorf(`illegal weekday %d in the list for "%c"`, wd, name)
+				return
+			}
+
+			bit := uint8(1) << uint(wd)
+			if df.set&bit != 0 {
+				err = fmt.Errorf(`duplicate weekday %d in the list for "%c"`, wd, name)
+				return
+			}
+			df.set |= bit
+		}
+
+		if b.Len() != 0 {
+			b.WriteByte(' ')
+		}
+
+		df.format(&b, name)
+	}
+
+	return NewWithOptions(b.String(), false, s.Options)
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
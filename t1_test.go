@@ -13,11 +13,15 @@ import (
 
 var testParameters = []struct {
 	pattern       string
+	options       *Options
 	errorExpected bool
 	t             time.Time
 	result        time.Time
 }{
 	{pattern: "ZZZYYY", errorExpected: true},
+	{pattern: "Y1 x M2", errorExpected: true},
+	{pattern: "Y1 M2 x", errorExpected: true},
+	{pattern: "x Y1 M2", errorExpected: true},
 	{pattern: "Z1M2D$-3W3w1h-4m+5s6", errorExpected: true},
 	{pattern: "YM2D$-3h-4m+5s6", errorExpected: true},
 	{pattern: "Y1M2D^$-3h-4m+5s6", errorExpected: true},
@@ -34,6 +38,11 @@ var testParameters = []struct {
 	{pattern: "D0", errorExpected: true},
 	{pattern: "M0", errorExpected: true},
 	{pattern: "W0", errorExpected: true},
+	{pattern: "K1", errorExpected: true},
+	{pattern: "K^1", errorExpected: true},
+	{pattern: "K$+1", errorExpected: true},
+	{pattern: "W+1 K+1", errorExpected: true},
+	{pattern: "K+1 W+1", errorExpected: true},
 	{pattern: "W^+0", errorExpected: true},
 	{pattern: "W$0", errorExpected: true},
 	{pattern: "W^-2", errorExpected: true},
@@ -43,6 +52,16 @@ var testParameters = []struct {
 	{pattern: "w8", errorExpected: true},
 	{pattern: "w$+3", errorExpected: true},
 	{pattern: "w^-3", errorExpected: true},
+	{pattern: "D!+1", errorExpected: true},
+	{pattern: "D!0", errorExpected: true},
+	{pattern: "M!2", errorExpected: true},
+	{pattern: "W!2", errorExpected: true},
+	{pattern: "M+2 Y+1 M+1", options: &Options{AllowAnyOrder: true}, errorExpected: true},
+	{pattern: "D$0", errorExpected: true},
+	{pattern: "D0", options: &Options{FromEndZeroBased: true}, errorExpected: true},
+	{pattern: "w-1", errorExpected: true},
+	{pattern: "w-8", options: &Options{WeekdayFromEnd: true}, errorExpected: true},
+	{pattern: "w-1,6", options: &Options{WeekdayFromEnd: true}, errorExpected: true},
 
 	{pattern: "", errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-13T14:55:22Z")},
 	{pattern: "", errorExpected: false, t: tConv("2020-06-13T14:55:21+03:00"), result: tConv("2020-06-13T14:55:21+03:00")},
@@ -127,6 +146,10 @@ var testParameters = []struct {
 
 	{pattern: "D+6 W-1 w2", errorExpected: false, t: tConv("2021-02-01T00:11:00Z"), result: tConv("2021-02-02T00:11:00Z")},
 	{pattern: "D+6 W+0 w2", errorExpected: false, t: tConv("2021-02-01T00:22:00Z"), result: tConv("2021-02-09T00:22:00Z")},
+	{pattern: "K+1", errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-20T14:55:22Z")},
+	{pattern: "K-2 w1", errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-05-25T14:55:22Z")},
+	{pattern: "D$1 K+1", errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-07-07T14:55:22Z")},
+	{pattern: "M+1 K-1 h0", errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-07-06T00:55:22Z")},
 	{pattern: "D+6 W-0 w2", errorExpected: false, t: tConv("2021-02-01T00:33:00Z"), result: tConv("2021-02-09T00:33:00Z")},
 	{pattern: "D+6 W+1 w2", errorExpected: false, t: tConv("2021-02-01T00:44:00Z"), result: tConv("2021-02-16T00:44:00Z")},
 
@@ -156,6 +179,248 @@ var testParameters = []struct {
 	{pattern: "W$4 w6", errorExpected: false, t: tConv("2021-03-20T00:00:00Z"), result: tConv("2021-03-06T00:00:00Z")},
 
 	{pattern: "l+10 u-2 n+1234", errorExpected: false, t: tConv("2021-03-20T00:00:00Z"), result: tConv("2021-03-20T00:00:00.009999234Z")},
+
+	{pattern: "M+2 Y+1", options: &Options{AllowAnyOrder: true}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2021-08-13T14:55:22Z")},
+	{pattern: "s-30 m+20 h-6 W-2 D$3 M+2 Y+1", options: &Options{AllowAnyOrder: true}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2021-08-15T09:14:52Z")},
+
+	{pattern: "D$0", options: &Options{FromEndZeroBased: true}, errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-02-28T14:55:22Z")},
+	{pattern: "D$1", options: &Options{FromEndZeroBased: true}, errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-02-27T14:55:22Z")},
+	{pattern: "D$28", options: &Options{FromEndZeroBased: true, ClampDay: true}, errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-02-01T14:55:22Z")},
+
+	{pattern: "M+1", options: &Options{PreserveMonthEnd: true}, errorExpected: false, t: tConv("2021-01-31T14:55:22Z"), result: tConv("2021-02-28T14:55:22Z")},
+	{pattern: "M+1", options: &Options{PreserveMonthEnd: true}, errorExpected: false, t: tConv("2020-01-31T14:55:22Z"), result: tConv("2020-02-29T14:55:22Z")},
+	{pattern: "Y+1 M+1", options: &Options{PreserveMonthEnd: true}, errorExpected: false, t: tConv("2019-01-31T14:55:22Z"), result: tConv("2020-02-29T14:55:22Z")},
+	{pattern: "M+1", options: &Options{PreserveMonthEnd: true}, errorExpected: false, t: tConv("2021-04-30T14:55:22Z"), result: tConv("2021-05-31T14:55:22Z")},
+	{pattern: "M-2", options: &Options{PreserveMonthEnd: true}, errorExpected: false, t: tConv("2021-02-28T14:55:22Z"), result: tConv("2020-12-31T14:55:22Z")},
+	{pattern: "M+1", options: &Options{PreserveMonthEnd: true}, errorExpected: false, t: tConv("2021-01-30T14:55:22Z"), result: tConv("2021-03-02T14:55:22Z")},
+	{pattern: "M+1 D5", options: &Options{PreserveMonthEnd: true}, errorExpected: false, t: tConv("2021-01-31T14:55:22Z"), result: tConv("2021-02-05T14:55:22Z")},
+	{pattern: "M3", options: &Options{PreserveMonthEnd: true}, errorExpected: false, t: tConv("2021-04-30T14:55:22Z"), result: tConv("2021-03-30T14:55:22Z")},
+	{pattern: "M+1", errorExpected: false, t: tConv("2021-01-31T14:55:22Z"), result: tConv("2021-03-03T14:55:22Z")},
+
+	{pattern: "W5", errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-02-01T14:55:22Z")},
+
+	{pattern: "h+25", errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-11T15:55:22Z")},
+	{pattern: "h+25 m+130", errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-11T18:05:22Z")},
+	{pattern: "h-49 m-61 s-3601", errorExpected: false, t: tConv("2021-03-01T00:30:00Z"), result: tConv("2021-02-26T21:28:59Z")},
+	{pattern: "s+86400", errorExpected: false, t: tConv("2020-12-31T23:59:59Z"), result: tConv("2021-01-01T23:59:59Z")},
+	{pattern: "m+527040", errorExpected: false, t: tConv("2020-01-01T00:00:00Z"), result: tConv("2021-01-01T00:00:00Z")},
+	{pattern: "l+1500 u+1500 n+1500", errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-10T14:55:23.5015015Z")},
+
+	{pattern: "M^+3", options: &Options{AllowMonthSnap: true}, errorExpected: false, t: tConv("2021-01-31T14:55:22.123Z"), result: tConv("2021-04-01T00:00:00Z")},
+	{pattern: "M^-1", options: &Options{AllowMonthSnap: true}, errorExpected: false, t: tConv("2021-03-31T14:55:22+03:00"), result: tConv("2021-02-01T00:00:00+03:00")},
+	{pattern: "Y+1 M^+1 D+4 h9 l+5", options: &Options{AllowMonthSnap: true}, errorExpected: false, t: tConv("2021-12-20T14:55:22.123Z"), result: tConv("2023-01-05T09:00:00.005Z")},
+	{pattern: "M^+1 D$1", options: &Options{AllowMonthSnap: true}, errorExpected: false, t: tConv("2021-01-20T14:55:22Z"), result: tConv("2021-02-28T00:00:00Z")},
+	{pattern: "M^+1 h+1", options: &Options{AllowMonthSnap: true}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-04-01T01:00:00Z")},
+	{pattern: "M^+1 h+1", options: &Options{AllowMonthSnap: true, RelativeFirst: true}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-04-01T01:00:00Z")},
+	{pattern: "M^+1 h9", options: &Options{AllowMonthSnap: true, RelativeFirst: true}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-04-01T09:00:00Z")},
+	{pattern: "M^+1", options: &Options{AllowMonthSnap: true, PreserveMonthEnd: true}, errorExpected: false, t: tConv("2021-01-31T14:55:22Z"), result: tConv("2021-02-01T00:00:00Z")},
+	{pattern: "M^+3", errorExpected: true},
+	{pattern: "M^3", options: &Options{AllowMonthSnap: true}, errorExpected: true},
+	{pattern: "M$+3", options: &Options{AllowMonthSnap: true}, errorExpected: true},
+	{pattern: "D^+3", options: &Options{AllowMonthSnap: true}, errorExpected: true},
+
+	{pattern: "m75", options: &Options{WrapMinuteSecond: true}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-10T14:15:22Z")},
+	{pattern: "m75", errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-10T15:15:22Z")},
+	{pattern: "h25 m75 s130", options: &Options{WrapMinuteSecond: true}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-11T01:15:10Z")},
+	{pattern: "m+75 s61", options: &Options{WrapMinuteSecond: true}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-10T16:10:01Z")},
+
+	{pattern: "d4", errorExpected: false, t: tConv("2021-03-14T14:55:22Z"), result: tConv("2021-03-11T14:55:22Z")},
+	{pattern: "w4", errorExpected: false, t: tConv("2021-03-14T14:55:22Z"), result: tConv("2021-03-18T14:55:22Z")},
+	{pattern: "d1", errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-08T14:55:22Z")},
+	{pattern: "d7", errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-14T14:55:22Z")},
+	{pattern: "W+1 d4", errorExpected: false, t: tConv("2021-03-14T14:55:22Z"), result: tConv("2021-03-18T14:55:22Z")},
+	{pattern: "d0", errorExpected: true},
+	{pattern: "d8", errorExpected: true},
+	{pattern: "d+1", errorExpected: true},
+	{pattern: "w1 d1", errorExpected: true},
+	{pattern: "W1 d1", errorExpected: true},
+	{pattern: "W^1 d1", errorExpected: true},
+	{pattern: "D15 d2", options: &Options{StrictWeekday: true}, errorExpected: true},
+
+	{pattern: "R2MO", errorExpected: false, t: tConv("2021-03-20T14:55:22Z"), result: tConv("2021-03-08T14:55:22Z")},
+	{pattern: "M+1 R1SU h9", errorExpected: false, t: tConv("2021-03-20T14:55:22Z"), result: tConv("2021-04-04T09:55:22Z")},
+	{pattern: "R-1FR", errorExpected: false, t: tConv("2021-03-20T14:55:22Z"), result: tConv("2021-03-26T14:55:22Z")},
+	{pattern: "R-2SU h0", errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-02-21T00:55:22Z")},
+	{pattern: "R-0FR", errorExpected: true},
+	{pattern: "R+1FR", errorExpected: true},
+	{pattern: "R-1XX", errorExpected: true},
+	{pattern: "R2XX", errorExpected: true},
+	{pattern: "R2mo", errorExpected: true},
+	{pattern: "R0MO", errorExpected: true},
+	{pattern: "R2MO w1", errorExpected: true},
+
+	{pattern: "W1 w1", options: &Options{FirstWeekRule: FirstWeekFull}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-01-04T14:55:22Z")},
+	{pattern: "W1 w5", options: &Options{FirstWeekRule: FirstWeekFull}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-01-08T14:55:22Z")},
+	{pattern: "W1 w5", options: &Options{FirstWeekRule: FirstWeekContainsThursday}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-01-08T14:55:22Z")},
+	{pattern: "W1 w5", options: &Options{FirstWeekRule: FirstWeekFromJan1}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-01-01T14:55:22Z")},
+	{pattern: "W1 w0", options: &Options{FirstWeekRule: FirstWeekFull}, errorExpected: false, t: tConv("2020-03-10T14:55:22Z"), result: tConv("2020-01-05T14:55:22Z")},
+	{pattern: "W1 w0", options: &Options{FirstWeekRule: FirstWeekContainsThursday}, errorExpected: false, t: tConv("2020-03-10T14:55:22Z"), result: tConv("2019-12-29T14:55:22Z")},
+	{pattern: "W2 w3", options: &Options{FirstWeekRule: FirstWeekContainsThursday}, errorExpected: false, t: tConv("2020-03-10T14:55:22Z"), result: tConv("2020-01-08T14:55:22Z")},
+	{pattern: "W2 w3", options: &Options{FirstWeekRule: FirstWeekContainsThursday, WeekFromInput: true}, errorExpected: false, t: tConv("2020-03-10T14:55:22Z"), result: tConv("2020-01-08T14:55:22Z")},
+	{pattern: "W10", options: &Options{FirstWeekRule: FirstWeekFull}, errorExpected: false, t: tConv("2020-01-01T14:55:22Z"), result: tConv("2020-03-11T14:55:22Z")},
+	{pattern: "Y2026 W1 w1", options: &Options{FirstWeekRule: FirstWeekContainsThursday}, errorExpected: false, t: tConv("2020-03-10T14:55:22Z"), result: tConv("2025-12-29T14:55:22Z")},
+
+	{pattern: "D15 W^1 w0", options: &Options{StrictConflicts: true}, errorExpected: true},
+	{pattern: "D$1 W$1 w5", options: &Options{StrictConflicts: true}, errorExpected: true},
+	{pattern: "D+1 W10 w1", options: &Options{StrictConflicts: true}, errorExpected: true},
+	{pattern: "M3 W10 w1", options: &Options{StrictConflicts: true}, errorExpected: true},
+	{pattern: "M3 W10 w1", options: &Options{StrictConflicts: true, WeekFromInput: true, FirstWeekRule: FirstWeekFull}, errorExpected: true},
+	{pattern: "M3 W^1 w0", options: &Options{StrictConflicts: true}, errorExpected: false, t: tConv("2021-01-20T14:55:22Z"), result: tConv("2021-03-07T14:55:22Z")},
+	{pattern: "D15 W^1", options: &Options{StrictConflicts: true}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-01T14:55:22Z")},
+	{pattern: "M3 D1 W2 w1", options: &Options{StrictConflicts: true, WeekFromInput: true}, errorExpected: false, t: tConv("2021-01-20T14:55:22Z"), result: tConv("2021-01-04T14:55:22Z")},
+	{pattern: "D15 W+1 w0", options: &Options{StrictConflicts: true}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-21T14:55:22Z")},
+	{pattern: "D15 W^1 w0", errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-07T14:55:22Z")},
+
+	{pattern: "D15 w2", options: &Options{StrictWeekday: true}, errorExpected: true},
+	{pattern: "D$1 w2", options: &Options{StrictWeekday: true}, errorExpected: true},
+	{pattern: "D15 w2", errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-16T14:55:22Z")},
+	{pattern: "D15 W+1 w2", options: &Options{StrictWeekday: true}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-23T14:55:22Z")},
+	{pattern: "D+1 w2", options: &Options{StrictWeekday: true}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-09T14:55:22Z")},
+	{pattern: "W^3 w2", options: &Options{StrictWeekday: true}, errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-03-16T14:55:22Z")},
+
+	{pattern: "D$1", options: &Options{BusinessDayConvention: BusinessDayFollowing}, errorExpected: false, t: tConv("2021-07-10T14:55:22Z"), result: tConv("2021-08-02T14:55:22Z")},
+	{pattern: "D$1", options: &Options{BusinessDayConvention: BusinessDayPreceding}, errorExpected: false, t: tConv("2021-07-10T14:55:22Z"), result: tConv("2021-07-30T14:55:22Z")},
+	{pattern: "D$1", options: &Options{BusinessDayConvention: BusinessDayModifiedFollowing}, errorExpected: false, t: tConv("2021-07-10T14:55:22Z"), result: tConv("2021-07-30T14:55:22Z")},
+	{pattern: "D1", options: &Options{BusinessDayConvention: BusinessDayFollowing}, errorExpected: false, t: tConv("2021-05-10T14:55:22Z"), result: tConv("2021-05-03T14:55:22Z")},
+	{pattern: "D1", options: &Options{BusinessDayConvention: BusinessDayPreceding}, errorExpected: false, t: tConv("2021-05-10T14:55:22Z"), result: tConv("2021-04-30T14:55:22Z")},
+	{pattern: "D1", options: &Options{BusinessDayConvention: BusinessDayModifiedFollowing}, errorExpected: false, t: tConv("2021-05-10T14:55:22Z"), result: tConv("2021-05-03T14:55:22Z")},
+	{pattern: "D30", options: &Options{BusinessDayConvention: BusinessDayModifiedFollowing}, errorExpected: false, t: tConv("2022-04-10T14:55:22Z"), result: tConv("2022-04-29T14:55:22Z")},
+	{pattern: "D15", options: &Options{BusinessDayConvention: BusinessDayFollowing}, errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-02-15T14:55:22Z")},
+	{pattern: "D+1", options: &Options{BusinessDayConvention: BusinessDayFollowing}, errorExpected: false, t: tConv("2021-07-30T14:55:22Z"), result: tConv("2021-07-31T14:55:22Z")},
+	{pattern: "D!1", options: &Options{BusinessDayConvention: BusinessDayPreceding}, errorExpected: false, t: tConv("2021-05-10T14:55:22Z"), result: tConv("2021-05-03T14:55:22Z")},
+	{pattern: "D$1", options: &Options{BusinessDayConvention: BusinessDayNone}, errorExpected: false, t: tConv("2021-07-10T14:55:22Z"), result: tConv("2021-07-31T14:55:22Z")},
+
+	{pattern: "w0,6", errorExpected: false, t: tConv("2021-02-22T14:55:22Z"), result: tConv("2021-02-27T14:55:22Z")},
+	{pattern: "w0,6", errorExpected: false, t: tConv("2021-02-27T14:55:22Z"), result: tConv("2021-02-27T14:55:22Z")},
+	{pattern: "w0,6", errorExpected: false, t: tConv("2021-02-28T14:55:22Z"), result: tConv("2021-02-28T14:55:22Z")},
+	{pattern: "w6,1 h9 m0 s0", errorExpected: false, t: tConv("2021-02-23T14:55:22Z"), result: tConv("2021-02-27T09:00:00Z")},
+	{pattern: "D$1 w1,2,3,4,5", errorExpected: false, t: tConv("2021-02-14T14:55:22Z"), result: tConv("2021-03-01T14:55:22Z")},
+	{pattern: "W1 w0,6", errorExpected: true},
+	{pattern: "W^1 w0,6", errorExpected: true},
+	{pattern: "D1,2", errorExpected: true},
+	{pattern: "w0,7", errorExpected: true},
+	{pattern: "w0,0", errorExpected: true},
+	{pattern: "w0,", errorExpected: true},
+
+	{pattern: "D!1 h9 m0 s0", errorExpected: false, t: tConv("2022-01-20T14:55:22Z"), result: tConv("2022-01-03T09:00:00Z")},
+	{pattern: "M+1 D!1 h9 m0 s0", errorExpected: false, t: tConv("2022-04-20T14:55:22Z"), result: tConv("2022-05-02T09:00:00Z")},
+	{pattern: "D!1 h9 m0 s0", errorExpected: false, t: tConv("2022-02-20T14:55:22Z"), result: tConv("2022-02-01T09:00:00Z")},
+
+	{pattern: "Y0 M2 D$1", errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("0000-02-29T14:55:22Z")},
+	{pattern: "Y1 M2 D29", errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("0001-03-01T14:55:22Z")},
+	{pattern: "Y-10000 M1 D1 h0 m0 s0", errorExpected: true},
+	{pattern: "Y1 M1 D1 h0 m0 s-30", errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("0000-12-31T23:59:52Z")},
+	{pattern: "Y1 M1 D1 h-15", errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("0000-12-31T23:55:22Z")},
+	{pattern: "Y9999 M12 D$1 l+1", errorExpected: false, t: tConv("2020-06-13T14:55:22.999Z"), result: tConv("9999-12-31T14:55:23Z")},
+	{pattern: "Y-5000 M2 D$1", errorExpected: false, t: time.Date(2020, 6, 13, 14, 55, 22, 0, time.UTC), result: time.Date(-2980, 2, 29, 14, 55, 22, 0, time.UTC)},
+	{pattern: "Y-5000 M2 D$1", errorExpected: false, t: time.Date(2021, 6, 13, 14, 55, 22, 0, time.UTC), result: time.Date(-2979, 2, 28, 14, 55, 22, 0, time.UTC)},
+	{pattern: "Y-2021 u+1", errorExpected: false, t: time.Date(2020, 6, 13, 14, 55, 22, 999999000, time.UTC), result: time.Date(-1, 6, 13, 14, 55, 23, 0, time.UTC)},
+	{pattern: "l+1 u+1", errorExpected: false, t: time.Date(1, 1, 1, 0, 0, 0, 998999000, time.UTC), result: time.Date(1, 1, 1, 0, 0, 1, 0, time.UTC)},
+	{pattern: "Y+10000", errorExpected: true},
+	{pattern: "Y10000", errorExpected: true},
+	{pattern: "Y-10000", errorExpected: true},
+	{pattern: "D99999999999", errorExpected: true},
+
+	{pattern: "S3661", errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-13T01:01:01Z")},
+	{pattern: "S90000", errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-14T01:00:00Z")},
+	{pattern: "S+3600", errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-13T15:55:22Z")},
+	{pattern: "D+1 S0 l0", errorExpected: false, t: tConv("2020-06-13T14:55:22.123Z"), result: tConv("2020-06-14T00:00:00Z")},
+	{pattern: "S90000", options: &Options{WrapTimeOfDay: true}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-13T01:00:00Z")},
+	{pattern: "h1 S0", errorExpected: true},
+	{pattern: "S0 s1", errorExpected: true},
+	{pattern: "S^1", errorExpected: true},
+	{pattern: "W1", errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-01-06T14:55:22Z")},
+	{pattern: "D1 W1", errorExpected: false, t: tConv("2021-03-10T14:55:22Z"), result: tConv("2021-01-04T14:55:22Z")},
+	{pattern: "W5", options: &Options{WeekFromInput: true}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-02-01T14:55:22Z")},
+
+	{pattern: "M2 D+30", options: &Options{RelativeFirst: true}, errorExpected: false, t: tConv("2021-01-15T14:55:22Z"), result: tConv("2021-02-14T14:55:22Z")},
+	{pattern: "M2 D+30", errorExpected: false, t: tConv("2021-01-15T14:55:22Z"), result: tConv("2021-03-17T14:55:22Z")},
+	{pattern: "h9 m+90", options: &Options{RelativeFirst: true}, errorExpected: false, t: tConv("2021-01-15T14:55:22Z"), result: tConv("2021-01-15T09:25:22Z")},
+	{pattern: "h9 m+90", errorExpected: false, t: tConv("2021-01-15T14:55:22Z"), result: tConv("2021-01-15T11:25:22Z")},
+	{pattern: "D$1 h+24", options: &Options{RelativeFirst: true}, errorExpected: false, t: tConv("2021-01-31T14:55:22Z"), result: tConv("2021-02-28T14:55:22Z")},
+	{pattern: "W+1 w2", options: &Options{RelativeFirst: true}, errorExpected: false, t: tConv("2021-02-01T04:00:00Z"), result: tConv("2021-02-09T04:00:00Z")},
+	{pattern: "Y+1 M+2 D+3 h-6", options: &Options{RelativeFirst: true}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2021-08-16T08:55:22Z")},
+
+	{pattern: "W^10 w5", options: &Options{ClampWeek: true}, errorExpected: false, t: tConv("2021-01-20T00:00:00Z"), result: tConv("2021-01-29T00:00:00Z")},
+	{pattern: "W^5 w5", options: &Options{ClampWeek: true}, errorExpected: false, t: tConv("2021-01-20T00:00:00Z"), result: tConv("2021-01-29T00:00:00Z")},
+	{pattern: "W^4 w5", options: &Options{ClampWeek: true}, errorExpected: false, t: tConv("2021-01-20T00:00:00Z"), result: tConv("2021-01-22T00:00:00Z")},
+	{pattern: "W$10 w5", options: &Options{ClampWeek: true}, errorExpected: false, t: tConv("2021-03-20T00:00:00Z"), result: tConv("2021-03-05T00:00:00Z")},
+	{pattern: "W$4 w4", options: &Options{ClampWeek: true}, errorExpected: false, t: tConv("2021-03-20T00:00:00Z"), result: tConv("2021-03-04T00:00:00Z")},
+
+	{pattern: "D31", options: &Options{ClampDay: true}, errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-02-28T14:55:22Z")},
+	{pattern: "D31", options: &Options{ClampDay: true}, errorExpected: false, t: tConv("2020-02-10T14:55:22Z"), result: tConv("2020-02-29T14:55:22Z")},
+	{pattern: "M+1 D31", options: &Options{ClampDay: true}, errorExpected: false, t: tConv("2021-03-31T14:55:22Z"), result: tConv("2021-04-30T14:55:22Z")},
+	{pattern: "D$40", options: &Options{ClampDay: true}, errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-02-01T14:55:22Z")},
+	{pattern: "D$28", options: &Options{ClampDay: true}, errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-02-01T14:55:22Z")},
+	{pattern: "D$29", options: &Options{ClampDay: true}, errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-02-01T14:55:22Z")},
+	{pattern: "D+30", options: &Options{ClampDay: true}, errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-03-12T14:55:22Z")},
+
+	{pattern: "W1 w5", options: &Options{WeekFromInput: true}, errorExpected: false, t: tConv("2021-03-10T00:00:00Z"), result: tConv("2021-01-01T00:00:00Z")},
+	{pattern: "W1 w4", options: &Options{WeekFromInput: true}, errorExpected: false, t: tConv("2021-03-10T00:00:00Z"), result: tConv("2021-01-07T00:00:00Z")},
+	{pattern: "W2 w4", errorExpected: false, t: tConv("2021-03-10T00:00:00Z"), result: tConv("2021-01-14T00:00:00Z")},
+	{pattern: "W2 w4", options: &Options{WeekFromInput: true}, errorExpected: false, t: tConv("2021-03-10T00:00:00Z"), result: tConv("2021-01-07T00:00:00Z")},
+	{pattern: "W1 w0", options: &Options{WeekFromInput: true}, errorExpected: false, t: tConv("2021-01-02T00:00:00Z"), result: tConv("2021-01-03T00:00:00Z")},
+	{pattern: "W1", options: &Options{WeekFromInput: true}, errorExpected: false, t: tConv("2021-01-02T00:00:00Z"), result: tConv("2021-01-02T00:00:00Z")},
+	{pattern: "W1", options: &Options{WeekFromInput: true}, errorExpected: false, t: tConv("2020-12-31T00:00:00Z"), result: tConv("2020-01-02T00:00:00Z")},
+	{pattern: "W1 w0", options: &Options{WeekFromInput: true}, errorExpected: false, t: tConv("2022-01-01T00:00:00Z"), result: tConv("2022-01-02T00:00:00Z")},
+	{pattern: "W53 w5", options: &Options{WeekFromInput: true}, errorExpected: false, t: tConv("2021-12-30T00:00:00Z"), result: tConv("2021-12-31T00:00:00Z")},
+	{pattern: "W53 w6", options: &Options{WeekFromInput: true}, errorExpected: false, t: tConv("2021-12-30T00:00:00Z"), result: tConv("2021-12-25T00:00:00Z")},
+	{pattern: "W53", options: &Options{WeekFromInput: true}, errorExpected: false, t: tConv("2021-01-02T00:00:00Z"), result: tConv("2021-12-25T00:00:00Z")},
+	{pattern: "W11", options: &Options{WeekFromInput: true}, errorExpected: false, t: tConv("2021-03-10T11:00:00Z"), result: tConv("2021-03-10T11:00:00Z")},
+	{pattern: "W13", options: &Options{WeekFromInput: true}, errorExpected: false, t: tConv("2021-03-10T11:00:00Z"), result: tConv("2021-03-24T11:00:00Z")},
+
+	{pattern: "h25", options: &Options{WrapTimeOfDay: true}, errorExpected: false, t: tConv("2021-03-20T14:55:22Z"), result: tConv("2021-03-20T01:55:22Z")},
+	{pattern: "D!1", errorExpected: false, t: tConv("2021-05-20T00:00:00Z"), result: tConv("2021-05-03T00:00:00Z")},
+	{pattern: "D!15", errorExpected: false, t: tConv("2021-05-20T00:00:00Z"), result: tConv("2021-05-14T00:00:00Z")},
+	{pattern: "D!16", errorExpected: false, t: tConv("2021-05-20T00:00:00Z"), result: tConv("2021-05-17T00:00:00Z")},
+	{pattern: "D!19", errorExpected: false, t: tConv("2021-05-20T00:00:00Z"), result: tConv("2021-05-19T00:00:00Z")},
+	{pattern: "D!30", errorExpected: false, t: tConv("2021-01-20T00:00:00Z"), result: tConv("2021-01-29T00:00:00Z")},
+	{pattern: "D!31", errorExpected: false, t: tConv("2021-01-20T00:00:00Z"), result: tConv("2021-01-29T00:00:00Z")},
+	{pattern: "D!1", errorExpected: false, t: tConv("2021-08-20T00:00:00Z"), result: tConv("2021-08-02T00:00:00Z")},
+	{pattern: "D!31", errorExpected: false, t: tConv("2021-04-20T00:00:00Z"), result: tConv("2021-04-30T00:00:00Z")},
+	{pattern: "D!31", errorExpected: false, t: tConv("2018-09-10T00:00:00Z"), result: tConv("2018-09-28T00:00:00Z")},
+	{pattern: "D!31", errorExpected: false, t: tConv("2019-11-10T00:00:00Z"), result: tConv("2019-11-29T00:00:00Z")},
+	{pattern: "D!31", errorExpected: false, t: tConv("2021-02-10T00:00:00Z"), result: tConv("2021-02-26T00:00:00Z")},
+	{pattern: "D!30", errorExpected: false, t: tConv("2021-02-10T00:00:00Z"), result: tConv("2021-02-26T00:00:00Z")},
+	{pattern: "D!29", errorExpected: false, t: tConv("2021-02-10T00:00:00Z"), result: tConv("2021-02-26T00:00:00Z")},
+	{pattern: "D!29", errorExpected: false, t: tConv("2024-02-10T00:00:00Z"), result: tConv("2024-02-29T00:00:00Z")},
+	{pattern: "D!30", errorExpected: false, t: tConv("2024-02-10T00:00:00Z"), result: tConv("2024-02-29T00:00:00Z")},
+
+	{pattern: "l123 u456 n789", errorExpected: false, t: tConv("2021-03-20T00:00:00.999Z"), result: tConv("2021-03-20T00:00:00.123456789Z")},
+	{pattern: "l123 u456 n+789", errorExpected: false, t: tConv("2021-03-20T00:00:00.999Z"), result: tConv("2021-03-20T00:00:00.123456789Z")},
+
+	{pattern: "D31", errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-03-03T14:55:22Z")},
+	{pattern: "D$29", errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-01-31T14:55:22Z")},
+	{pattern: "M+1 D$1", errorExpected: false, t: tConv("2021-01-31T14:55:22Z"), result: tConv("2021-03-03T14:55:22Z")},
+	{pattern: "M+1 D$1", options: &Options{ClampDay: true}, errorExpected: false, t: tConv("2021-01-31T14:55:22Z"), result: tConv("2021-02-28T14:55:22Z")},
+
+	{pattern: "h25", errorExpected: false, t: tConv("2021-03-20T14:55:22Z"), result: tConv("2021-03-21T01:55:22Z")},
+	{pattern: "D31 h49 m75 s61", options: &Options{WrapTimeOfDay: true}, errorExpected: false, t: tConv("2021-03-20T14:55:22Z"), result: tConv("2021-03-31T01:15:01Z")},
+	{pattern: "h25 m+70", options: &Options{WrapTimeOfDay: true}, errorExpected: false, t: tConv("2021-03-20T14:55:22Z"), result: tConv("2021-03-20T03:05:22Z")},
+	{pattern: "h+25", options: &Options{WrapTimeOfDay: true}, errorExpected: false, t: tConv("2021-03-20T14:55:22Z"), result: tConv("2021-03-21T15:55:22Z")},
+	{pattern: "w-1", options: &Options{WeekdayFromEnd: true}, errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-02-13T14:55:22Z")},
+	{pattern: "w-7", options: &Options{WeekdayFromEnd: true}, errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-02-07T14:55:22Z")},
+	{pattern: "W^2 w-2", options: &Options{WeekdayFromEnd: true}, errorExpected: false, t: tConv("2021-02-10T14:55:22Z"), result: tConv("2021-02-12T14:55:22Z")},
+	{pattern: "h2", options: &Options{AbsoluteDirection: AbsoluteForward}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-14T02:55:22Z")},
+	{pattern: "h2", options: &Options{AbsoluteDirection: AbsoluteBackward}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-13T02:55:22Z")},
+	{pattern: "h20", options: &Options{AbsoluteDirection: AbsoluteBackward}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-12T20:55:22Z")},
+	{pattern: "h20", options: &Options{AbsoluteDirection: AbsoluteForward}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-13T20:55:22Z")},
+	{pattern: "h3", options: &Options{AbsoluteDirection: AbsoluteNearest}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-13T03:55:22Z")},
+	{pattern: "h2 m0", options: &Options{AbsoluteDirection: AbsoluteNearest}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-14T02:00:22Z")},
+	{pattern: "m10", options: &Options{AbsoluteDirection: AbsoluteForward}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-13T15:10:22Z")},
+	{pattern: "D1 h2", options: &Options{AbsoluteDirection: AbsoluteForward}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-01T02:55:22Z")},
+	{pattern: "D+1 h2", options: &Options{AbsoluteDirection: AbsoluteForward}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-15T02:55:22Z")},
+	{pattern: "h14 m55 s22", options: &Options{AbsoluteDirection: AbsoluteForward}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-13T14:55:22Z")},
+	{pattern: "S3600", options: &Options{AbsoluteDirection: AbsoluteForward}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-14T01:00:00Z")},
+	{pattern: "h+2", options: &Options{AbsoluteDirection: AbsoluteForward}, errorExpected: false, t: tConv("2020-06-13T14:55:22Z"), result: tConv("2020-06-13T16:55:22Z")},
+	{pattern: "W^1 w6", options: &Options{WeekOfMonthRule: WeekOfMonthFirstOccurrence}, errorExpected: false, t: tConv("2021-05-20T14:55:22Z"), result: tConv("2021-05-01T14:55:22Z")},
+	{pattern: "W^1 w6", options: &Options{WeekOfMonthRule: WeekOfMonthFull}, errorExpected: false, t: tConv("2021-05-20T14:55:22Z"), result: tConv("2021-05-08T14:55:22Z")},
+	{pattern: "W^1 w0", options: &Options{WeekOfMonthRule: WeekOfMonthFull}, errorExpected: false, t: tConv("2021-05-20T14:55:22Z"), result: tConv("2021-05-02T14:55:22Z")},
+	{pattern: "W^2 w1", options: &Options{WeekOfMonthRule: WeekOfMonthFull}, errorExpected: false, t: tConv("2021-05-20T14:55:22Z"), result: tConv("2021-05-10T14:55:22Z")},
+	{pattern: "W^5 w6", options: &Options{WeekOfMonthRule: WeekOfMonthFull}, errorExpected: false, t: tConv("2021-05-20T14:55:22Z"), result: tConv("2021-06-05T14:55:22Z")},
+	{pattern: "W^5 w6", options: &Options{WeekOfMonthRule: WeekOfMonthFull, ClampWeek: true}, errorExpected: false, t: tConv("2021-05-20T14:55:22Z"), result: tConv("2021-05-29T14:55:22Z")},
+	{pattern: "W$1 w6", options: &Options{WeekOfMonthRule: WeekOfMonthFull}, errorExpected: false, t: tConv("2021-05-20T14:55:22Z"), result: tConv("2021-05-29T14:55:22Z")},
+	{pattern: "W^1 w3", options: &Options{WeekOfMonthRule: WeekOfMonthFull}, errorExpected: false, t: tConv("2021-08-20T14:55:22Z"), result: tConv("2021-08-04T14:55:22Z")},
 }
 
 func tConv(s string) time.Time {
@@ -170,7 +435,7 @@ func tConv(s string) time.Time {
 
 func Test1(t *testing.T) {
 	for i, p := range testParameters {
-		ts, err := New(p.pattern, false)
+		ts, err := NewWithOptions(p.pattern, false, p.options)
 
 		if p.errorExpected {
 			if err == nil {
@@ -224,11 +489,32 @@ func benchmark(b *testing.B, cached bool) {
 	}
 }
 
+func BenchmarkSubSecond(b *testing.B) {
+	pattern := "h12 m0 s0 l123 u456 n789"
+	t := tConv("2020-06-13T14:55:22Z")
+	expected := tConv("2020-06-13T12:00:00.123456789Z")
+
+	ts, err := New(pattern, false)
+	if err != nil {
+		b.Fatalf(`"%s" prepared with error: %s`, pattern, err)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		result := ts.Exec(t)
+
+		if result != expected { // the locale must be saved!
+			b.Fatalf(`[%d] "%s" shifted by "%s": got "%s", expected "%s"`, i, misc.Time2JSONtz(t), pattern, misc.Time2JSONtz(result), misc.Time2JSONtz(expected))
+		}
+	}
+}
+
 //----------------------------------------------------------------------------------------------------------------------------//
 
 func TestPrintParameters(t *testing.T) {
 	for _, p := range testParameters {
-		if !p.errorExpected {
+		if !p.errorExpected && p.options == nil {
 			fmt.Printf("|\"%s\"|%s|%s|\n", p.pattern, misc.Time2JSONtz(p.t), misc.Time2JSONtz(p.result))
 		}
 	}
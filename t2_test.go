@@ -0,0 +1,2417 @@
+package timeshift
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alrusov/misc"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestRequireLocation(t *testing.T) {
+	ts, err := NewWithOptions("h+1", false, &Options{RequireLocation: time.UTC})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := tConv("2020-06-13T14:55:22Z")
+	result, err := ts.ExecChecked(src)
+	if err != nil {
+		t.Errorf(`"%s": unexpected error: %s`, src, err)
+	} else if expected := tConv("2020-06-13T15:55:22Z"); result != expected {
+		t.Errorf(`"%s": got "%s", expected "%s"`, src, result, expected)
+	}
+
+	src = tConv("2020-06-13T14:55:22+03:00")
+	_, err = ts.ExecChecked(src)
+	if err == nil {
+		t.Errorf(`"%s": processed without error, expected error`, src)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestCancelsWith(t *testing.T) {
+	list := []struct {
+		a        string
+		b        string
+		expected bool
+	}{
+		{a: "", b: "", expected: true},
+		{a: "Y+1 M-2 h+3", b: "Y-1 M+2 h-3", expected: true},
+		{a: "Y+1 M-2 h+3", b: "Y-1 M+2 h-2", expected: false},
+		{a: "Y+1 M-2 h+3", b: "Y-1 M+2", expected: false},
+		{a: "Y+1", b: "Y1", expected: false},
+		{a: "D$1", b: "D$1", expected: false},
+		{a: "W+1 w2", b: "W-1 w2", expected: false},
+	}
+
+	for i, p := range list {
+		a, err := New(p.a, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.a, err)
+		}
+
+		b, err := New(p.b, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.b, err)
+		}
+
+		if got := a.CancelsWith(b); got != p.expected {
+			t.Errorf(`[%d] "%s" cancels with "%s": got %v, expected %v`, i, p.a, p.b, got, p.expected)
+		}
+	}
+
+	a, _ := New("h+1", false)
+	a, err := a.AndThen("D+1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := New("h-1", false)
+	if a.CancelsWith(b) || b.CancelsWith(a) {
+		t.Error(`"h+1" then "D+1" must not cancel with "h-1"`)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestCompare(t *testing.T) {
+	// in the expected order
+	patterns := []string{"", "n+1", "h-1", "h+1", "h1", "D-1", "D$1", "M+1 D1", "M+1 D2", "Y+1"}
+
+	list := make([]*TimeShift, 0, len(patterns)+1)
+	for i := len(patterns) - 1; i >= 0; i-- {
+		ts, err := New(patterns[i], false)
+		if err != nil {
+			t.Fatalf(`"%s": %s`, patterns[i], err)
+		}
+		list = append(list, ts)
+	}
+	list = append(list, nil)
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return Compare(list[i], list[j]) < 0
+	})
+
+	if Compare(list[0], list[1]) != 0 {
+		t.Error("nil and the empty shift must be equal")
+	}
+
+	for i, ts := range list[1:] {
+		if s := ts.String(); s != patterns[i] {
+			t.Errorf(`[%d] got "%s", expected "%s"`, i, s, patterns[i])
+		}
+	}
+
+	a, _ := New("Y+1 M2", false)
+	b, _ := New("Y+1M2", true)
+	if Compare(a, b) != 0 || Compare(b, a) != 0 {
+		t.Error(`"Y+1 M2" must be equal to "Y+1M2"`)
+	}
+
+	c, _ := a.AndThen("D+1")
+	d, _ := a.AndThen("D+2")
+	if Compare(a, c) != -1 || Compare(c, a) != 1 {
+		t.Error(`the shift without stages must be less`)
+	}
+	if Compare(c, d) != -1 || Compare(d, c) != 1 {
+		t.Error(`"D+1" stage must be less than "D+2"`)
+	}
+
+	e, _ := b.AndThen("D+1")
+	if Compare(c, e) != 0 {
+		t.Error(`the same stages must be equal`)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestDiffPatterns(t *testing.T) {
+	diff, err := DiffPatterns("Y+1 M2 D$3 h9", "  Y+1M3 W^2 w1 h9 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []PartDiff{
+		{Letter: 'M', Kind: PartChanged, Before: "M2", After: "M3"},
+		{Letter: 'D', Kind: PartRemoved, Before: "D$3"},
+		{Letter: 'W', Kind: PartAdded, After: "W^2"},
+		{Letter: 'w', Kind: PartAdded, After: "w1"},
+	}
+
+	if len(diff) != len(expected) {
+		t.Fatalf("got %v, expected %v", diff, expected)
+	}
+
+	for i, d := range diff {
+		if d != expected[i] {
+			t.Errorf("[%d] got %v, expected %v", i, d, expected[i])
+		}
+	}
+
+	if diff, err = DiffPatterns("", " "); err != nil || len(diff) != 0 {
+		t.Errorf("got %v, %v, expected no difference", diff, err)
+	}
+
+	if _, err = DiffPatterns("Y+1", "Y+1 Y2"); err == nil {
+		t.Error("error expected")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestPartCount(t *testing.T) {
+	list := map[string]int{
+		"":                         0,
+		"Y+1":                      1,
+		"Y+1 M+2 D$3 W-2 h-6 m+20": 6,
+		"W^2 w1 h9 m0 s0 l0 u0 n0": 8,
+	}
+
+	for pattern, expected := range list {
+		ts, err := New(pattern, false)
+		if err != nil {
+			t.Fatalf(`"%s": %s`, pattern, err)
+		}
+
+		if n := ts.PartCount(); n != expected {
+			t.Errorf(`"%s": got %d, expected %d`, pattern, n, expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestMatches(t *testing.T) {
+	list := []struct {
+		pattern  string
+		t        time.Time
+		expected bool
+	}{
+		{pattern: "", t: tConv("2021-03-20T14:55:22Z"), expected: true},
+		{pattern: "Y+1 h-3", t: tConv("2021-03-20T14:55:22Z"), expected: true},
+		{pattern: "h14 m55", t: tConv("2021-03-20T14:55:22Z"), expected: true},
+		{pattern: "h14 m55 s0", t: tConv("2021-03-20T14:55:22Z"), expected: false},
+		{pattern: "Y+1 h14 m55", t: tConv("2021-03-20T14:55:22Z"), expected: true},
+		{pattern: "D$1", t: tConv("2021-03-31T14:55:22Z"), expected: true},
+		{pattern: "D$1", t: tConv("2021-03-30T14:55:22Z"), expected: false},
+		{pattern: "W$1 w5", t: tConv("2021-03-26T00:00:00Z"), expected: true},
+		{pattern: "W$1 w5", t: tConv("2021-03-19T00:00:00Z"), expected: false},
+		{pattern: "w6", t: tConv("2021-03-20T00:00:00Z"), expected: true},
+		{pattern: "w0,6", t: tConv("2021-03-20T00:00:00Z"), expected: true},
+		{pattern: "w0,6", t: tConv("2021-03-19T00:00:00Z"), expected: false},
+		{pattern: "D31", t: tConv("2021-02-28T00:00:00Z"), expected: false},
+	}
+
+	for i, p := range list {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		if got := ts.Matches(p.t); got != p.expected {
+			t.Errorf(`[%d] "%s" matches "%s": got %v, expected %v`, i, p.pattern, p.t, got, p.expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestResolvedWeekday(t *testing.T) {
+	list := []struct {
+		pattern string
+		options *Options
+		then    string
+		t       time.Time
+		wd      time.Weekday
+		ok      bool
+	}{
+		{pattern: "", t: tConv("2021-03-10T14:55:22Z"), wd: time.Wednesday, ok: false},
+		{pattern: "D+1", t: tConv("2021-03-10T14:55:22Z"), wd: time.Thursday, ok: false},
+		{pattern: "w0", t: tConv("2021-03-10T14:55:22Z"), wd: time.Sunday, ok: true},
+		{pattern: "W^2 w5", t: tConv("2021-03-10T14:55:22Z"), wd: time.Friday, ok: true},
+		{pattern: "W5", t: tConv("2020-06-13T14:55:22Z"), wd: time.Saturday, ok: true},
+		{pattern: "D1 W$1", t: tConv("2021-03-10T14:55:22Z"), wd: time.Monday, ok: true},
+		{pattern: "W+1", t: tConv("2021-03-10T14:55:22Z"), wd: time.Wednesday, ok: true},
+		{pattern: "w0", then: "D+1", t: tConv("2021-03-10T14:55:22Z"), wd: time.Monday, ok: true},
+		{pattern: "w0 h+25", then: "h-48", t: tConv("2021-03-10T14:55:22Z"), wd: time.Friday, ok: true},
+		{pattern: "w-1", options: &Options{WeekdayFromEnd: true}, t: tConv("2021-03-10T14:55:22Z"), wd: time.Saturday, ok: true},
+		{pattern: "w3 h9", options: &Options{AbsoluteDirection: AbsoluteForward}, t: tConv("2021-03-10T14:55:22Z"), wd: time.Wednesday, ok: true},
+		{pattern: "h9", options: &Options{AbsoluteDirection: AbsoluteForward}, t: tConv("2021-03-10T14:55:22Z"), wd: time.Thursday, ok: false},
+	}
+
+	for i, p := range list {
+		ts, err := NewWithOptions(p.pattern, false, p.options)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		if p.then != "" {
+			ts, err = ts.AndThen(p.then)
+			if err != nil {
+				t.Fatalf(`[%d] "%s": %s`, i, p.then, err)
+			}
+		}
+
+		wd, ok := ts.ResolvedWeekday(p.t)
+		if wd != p.wd || ok != p.ok {
+			t.Errorf(`[%d] "%s" on "%s": got %s, %v, expected %s, %v`, i, p.pattern, p.t, wd, ok, p.wd, p.ok)
+		}
+
+		if r := ts.Exec(p.t).Weekday(); r != wd {
+			t.Errorf(`[%d] "%s" on "%s": Exec gives %s, resolved %s`, i, p.pattern, p.t, r, wd)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestDeterministic(t *testing.T) {
+	for _, pattern := range []string{"", "Y+1 M2 D$1 W^2 w5 S+1 l0 u0 n0"} {
+		ts, err := New(pattern, true)
+		if err != nil {
+			t.Fatalf(`"%s": %s`, pattern, err)
+		}
+
+		if !ts.Deterministic() {
+			t.Errorf(`"%s": deterministic expected`, pattern)
+		}
+	}
+
+	if !(*TimeShift)(nil).Deterministic() {
+		t.Error(`nil: deterministic expected`)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestPipe(t *testing.T) {
+	ts, err := New("D+1 h0", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := "2021-03-20T14:55:22Z\n2021-03-31T10:00:00.123+03:00\n"
+	expected := "2021-03-21T00:55:22Z\n2021-04-01T00:00:00.123+03:00\n"
+
+	var dst bytes.Buffer
+	err = ts.Pipe(strings.NewReader(src), &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dst.String() != expected {
+		t.Errorf(`got "%s", expected "%s"`, dst.String(), expected)
+	}
+
+	dst.Reset()
+	err = ts.Pipe(strings.NewReader(src+"2021-03-20 14:55:22\n2021-03-20T14:55:22Z\n"), &dst)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Errorf(`got error "%v", expected error about line 3`, err)
+	}
+
+	if dst.String() != expected {
+		t.Errorf(`got "%s" before the bad line, expected "%s"`, dst.String(), expected)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestMatchesInMonth(t *testing.T) {
+	list := []struct {
+		pattern  string
+		year     int
+		month    time.Month
+		expected []time.Time
+	}{
+		{pattern: "W^2 w2", year: 2021, month: time.March, expected: []time.Time{tConv("2021-03-09T00:00:00Z")}},
+		{pattern: "W^2 w2 h9", year: 2021, month: time.March, expected: []time.Time{tConv("2021-03-09T09:00:00Z")}},
+		{pattern: "W$1 w5", year: 2021, month: time.March, expected: []time.Time{tConv("2021-03-26T00:00:00Z")}},
+		{pattern: "W^5 w1", year: 2021, month: time.March, expected: []time.Time{tConv("2021-03-29T00:00:00Z")}},
+		{pattern: "W^5 w1", year: 2021, month: time.February, expected: nil},
+		{pattern: "W$5 w1", year: 2021, month: time.February, expected: nil},
+		{pattern: "W2 w2", year: 2021, month: time.March, expected: nil},
+		{pattern: "", year: 2021, month: time.March, expected: nil},
+	}
+
+	for i, p := range list {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		got := ts.MatchesInMonth(p.year, p.month, time.UTC)
+		if len(got) != len(p.expected) {
+			t.Errorf(`[%d] "%s" in %d-%02d: got %v, expected %v`, i, p.pattern, p.year, p.month, got, p.expected)
+			continue
+		}
+
+		for j := range got {
+			if got[j] != p.expected[j] {
+				t.Errorf(`[%d] "%s" in %d-%02d: got %v, expected %v`, i, p.pattern, p.year, p.month, got, p.expected)
+				break
+			}
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Run it with -race
+func TestConcurrentExec(t *testing.T) {
+	pattern := "Y+1 M+2 D$3 W-2 h-6 m+20 s-30"
+	src := tConv("2020-06-13T14:55:22Z")
+	expected := tConv("2021-08-15T09:14:52Z")
+
+	ts, err := New(pattern, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 1000; j++ {
+				ts2, err := New(pattern, true)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				if ts2 != ts {
+					t.Errorf(`"%s": got another cached object`, pattern)
+					return
+				}
+
+				if result := ts2.Exec(src); result != expected {
+					t.Errorf(`"%s": got "%s", expected "%s"`, pattern, result, expected)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFinestUnit(t *testing.T) {
+	list := []struct {
+		pattern  string
+		expected byte
+	}{
+		{pattern: "", expected: 0},
+		{pattern: "Y+1", expected: 'Y'},
+		{pattern: "Y+1 M2", expected: 'M'},
+		{pattern: "D1 W1", expected: 'D'},
+		{pattern: "W^2 w1", expected: 'w'},
+		{pattern: "W-1", expected: 'W'},
+		{pattern: "Y1 h3 m+5", expected: 'm'},
+		{pattern: "D1 S0", expected: 'S'},
+		{pattern: "h1 l0 n+1", expected: 'n'},
+	}
+
+	for i, p := range list {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		if u := ts.FinestUnit(); u != p.expected {
+			t.Errorf(`[%d] "%s": got "%c", expected "%c"`, i, p.pattern, u, p.expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExecRange(t *testing.T) {
+	list := []struct {
+		pattern string
+		t       time.Time
+		start   time.Time
+		end     time.Time
+	}{
+		{pattern: "", t: tConv("2021-03-10T14:55:22Z"), start: tConv("2021-03-10T14:55:22Z"), end: tConv("2021-03-10T14:55:22Z")},
+		{pattern: "Y-1", t: tConv("2021-03-10T14:55:22Z"), start: tConv("2020-01-01T00:00:00Z"), end: tConv("2021-01-01T00:00:00Z")},
+		{pattern: "M-1", t: tConv("2021-03-10T14:55:22+03:00"), start: tConv("2021-02-01T00:00:00+03:00"), end: tConv("2021-03-01T00:00:00+03:00")},
+		{pattern: "M12", t: tConv("2021-03-10T14:55:22Z"), start: tConv("2021-12-01T00:00:00Z"), end: tConv("2022-01-01T00:00:00Z")},
+		{pattern: "D-1", t: tConv("2021-03-01T14:55:22Z"), start: tConv("2021-02-28T00:00:00Z"), end: tConv("2021-03-01T00:00:00Z")},
+		{pattern: "W-1", t: tConv("2021-03-10T14:55:22Z"), start: tConv("2021-02-28T00:00:00Z"), end: tConv("2021-03-07T00:00:00Z")},
+		{pattern: "W^2 w1", t: tConv("2021-03-10T14:55:22Z"), start: tConv("2021-03-08T00:00:00Z"), end: tConv("2021-03-09T00:00:00Z")},
+		{pattern: "h+1", t: tConv("2021-03-10T23:55:22Z"), start: tConv("2021-03-11T00:00:00Z"), end: tConv("2021-03-11T01:00:00Z")},
+		{pattern: "m-5", t: tConv("2021-03-10T14:55:22Z"), start: tConv("2021-03-10T14:50:00Z"), end: tConv("2021-03-10T14:51:00Z")},
+		{pattern: "s0", t: tConv("2021-03-10T14:55:22.123Z"), start: tConv("2021-03-10T14:55:00Z"), end: tConv("2021-03-10T14:55:01Z")},
+		{pattern: "D1 S+1", t: tConv("2021-03-10T14:55:22.123Z"), start: tConv("2021-03-01T14:55:23Z"), end: tConv("2021-03-01T14:55:24Z")},
+	}
+
+	for i, p := range list {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		start, end := ts.ExecRange(p.t)
+		if start != p.start || end != p.end {
+			t.Errorf(`[%d] "%s" on "%s": got ["%s", "%s"), expected ["%s", "%s")`, i, p.pattern, p.t, start, end, p.start, p.end)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExecDateTimeOnly(t *testing.T) {
+	list := []struct {
+		pattern  string
+		options  *Options
+		t        time.Time
+		dateOnly time.Time
+		timeOnly time.Time
+	}{
+		{pattern: "", t: tConv("2021-03-10T14:55:22.123Z"), dateOnly: tConv("2021-03-10T14:55:22.123Z"), timeOnly: tConv("2021-03-10T14:55:22.123Z")},
+		{pattern: "Y+1 M+2 D$3 W-2 h-6 m+20 s-30", t: tConv("2020-06-13T14:55:22Z"), dateOnly: tConv("2021-08-15T14:55:22Z"), timeOnly: tConv("2020-06-13T09:14:52Z")},
+		{pattern: "D1 h+25 l0", t: tConv("2021-03-10T14:55:22.123+03:00"), dateOnly: tConv("2021-03-01T14:55:22.123+03:00"), timeOnly: tConv("2021-03-10T15:55:22+03:00")},
+		{pattern: "W^1 w1 h9 m0 s0", t: tConv("2021-03-10T14:55:22Z"), dateOnly: tConv("2021-03-01T14:55:22Z"), timeOnly: tConv("2021-03-10T09:00:00Z")},
+		{pattern: "M^+1 h9", options: &Options{AllowMonthSnap: true}, t: tConv("2021-03-10T14:55:22Z"), dateOnly: tConv("2021-04-01T14:55:22Z"), timeOnly: tConv("2021-03-10T09:55:22Z")},
+	}
+
+	for i, p := range list {
+		ts, err := NewWithOptions(p.pattern, false, p.options)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		if r := ts.ExecDateOnly(p.t); r != p.dateOnly {
+			t.Errorf(`[%d] "%s" date only on "%s": got "%s", expected "%s"`, i, p.pattern, p.t, r, p.dateOnly)
+		}
+
+		if r := ts.ExecTimeOnly(p.t); r != p.timeOnly {
+			t.Errorf(`[%d] "%s" time only on "%s": got "%s", expected "%s"`, i, p.pattern, p.t, r, p.timeOnly)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExecDelta(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	list := []struct {
+		pattern string
+		t       time.Time
+		delta   time.Duration
+	}{
+		{pattern: "", t: tConv("2021-03-10T14:55:22Z"), delta: 0},
+		{pattern: "h+1 m-30", t: tConv("2021-03-10T14:55:22Z"), delta: 30 * time.Minute},
+		{pattern: "D-1", t: tConv("2021-03-10T14:55:22Z"), delta: -24 * time.Hour},
+		{pattern: "D+1", t: time.Date(2021, 3, 13, 12, 0, 0, 0, ny), delta: 23 * time.Hour},
+		{pattern: "D+1", t: time.Date(2021, 11, 6, 12, 0, 0, 0, ny), delta: 25 * time.Hour},
+	}
+
+	for i, p := range list {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		r, d := ts.ExecDelta(p.t)
+		if !r.Equal(ts.Exec(p.t)) || d != p.delta {
+			t.Errorf(`[%d] "%s" on "%s": got "%s" (%s), expected %s`, i, p.pattern, p.t, r, d, p.delta)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExecDate(t *testing.T) {
+	list := []struct {
+		pattern  string
+		src      [3]int
+		expected [3]int
+	}{
+		{pattern: "", src: [3]int{2021, 2, 30}, expected: [3]int{2021, 3, 2}},
+		{pattern: "h-15 m+5", src: [3]int{2021, 3, 10}, expected: [3]int{2021, 3, 10}},
+		{pattern: "D+1 h-25", src: [3]int{2021, 2, 28}, expected: [3]int{2021, 3, 1}},
+		{pattern: "Y+1 M+2 D$3 W-2 h-6", src: [3]int{2020, 6, 13}, expected: [3]int{2021, 8, 15}},
+		{pattern: "W^1 w1 h9", src: [3]int{2021, 3, 10}, expected: [3]int{2021, 3, 1}},
+		{pattern: "Y-2021", src: [3]int{2021, 1, 1}, expected: [3]int{0, 1, 1}},
+	}
+
+	for i, p := range list {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		y, m, d := ts.ExecDate(p.src[0], time.Month(p.src[1]), p.src[2])
+		if y != p.expected[0] || int(m) != p.expected[1] || d != p.expected[2] {
+			t.Errorf(`[%d] "%s" on %v: got %d-%d-%d, expected %v`, i, p.pattern, p.src, y, m, d, p.expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExecResolve(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	list := []struct {
+		pattern   string
+		t         time.Time
+		ambiguity Ambiguity
+	}{
+		{pattern: "", t: time.Date(2021, 3, 14, 10, 0, 0, 0, ny), ambiguity: AmbiguityNone},
+		{pattern: "h2 m30", t: time.Date(2021, 3, 14, 10, 0, 0, 0, ny), ambiguity: AmbiguityGap},
+		{pattern: "h1 m30", t: time.Date(2021, 3, 14, 10, 0, 0, 0, ny), ambiguity: AmbiguityNone},
+		{pattern: "D+1 h2 m30", t: time.Date(2021, 3, 13, 10, 0, 0, 0, ny), ambiguity: AmbiguityGap},
+		{pattern: "h1 m30", t: time.Date(2021, 11, 7, 10, 0, 0, 0, ny), ambiguity: AmbiguityFold},
+		{pattern: "h2 m30", t: time.Date(2021, 11, 7, 10, 0, 0, 0, ny), ambiguity: AmbiguityNone},
+		{pattern: "h1 m30", t: time.Date(2021, 11, 7, 10, 0, 0, 0, time.UTC), ambiguity: AmbiguityNone},
+	}
+
+	for i, p := range list {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		r, a := ts.ExecResolve(p.t)
+		if a != p.ambiguity || !r.Equal(ts.Exec(p.t)) {
+			t.Errorf(`[%d] "%s" on "%s": got "%s", %d, expected %d`, i, p.pattern, p.t, r, a, p.ambiguity)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExecIfChanged(t *testing.T) {
+	list := []struct {
+		pattern string
+		t       time.Time
+		changed bool
+	}{
+		{pattern: "", t: tConv("2021-03-10T14:55:22Z"), changed: false},
+		{pattern: "D+0", t: tConv("2021-03-10T14:55:22Z"), changed: false},
+		{pattern: "h14 m55", t: tConv("2021-03-10T14:55:22Z"), changed: false},
+		{pattern: "h14 m55 s0", t: tConv("2021-03-10T14:55:22Z"), changed: true},
+		{pattern: "D$1", t: tConv("2021-03-31T14:55:22Z"), changed: false},
+		{pattern: "D$1", t: tConv("2021-03-30T14:55:22Z"), changed: true},
+	}
+
+	for i, p := range list {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		r, changed := ts.ExecIfChanged(p.t)
+		if changed != p.changed || r != ts.Exec(p.t) {
+			t.Errorf(`[%d] "%s" on "%s": got "%s", %v, expected %v`, i, p.pattern, p.t, r, changed, p.changed)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestNilReceiver(t *testing.T) {
+	var ts *TimeShift
+	src := tConv("2021-03-10T14:55:22Z")
+
+	if r := ts.Exec(src); r != src {
+		t.Errorf(`Exec: got "%s", expected "%s"`, r, src)
+	}
+
+	if r, err := ts.ExecChecked(src); err != nil || r != src {
+		t.Errorf(`ExecChecked: got "%s" (%v), expected "%s"`, r, err, src)
+	}
+
+	if r := ts.ExecDateOnly(src); r != src {
+		t.Errorf(`ExecDateOnly: got "%s", expected "%s"`, r, src)
+	}
+
+	if n := ts.PartCount(); n != 0 {
+		t.Errorf(`PartCount: got %d, expected 0`, n)
+	}
+
+	if !ts.Matches(src) {
+		t.Errorf(`Matches: got false, expected true`)
+	}
+
+	empty, _ := New("", false)
+	if !ts.CancelsWith(empty) || !empty.CancelsWith(ts) {
+		t.Errorf(`CancelsWith: got false, expected true`)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestNewFromMap(t *testing.T) {
+	src := tConv("2020-06-13T14:55:22Z")
+
+	list := []struct {
+		m             map[string]string
+		errorExpected bool
+		result        time.Time
+	}{
+		{m: map[string]string{"year": "+1", "month": "+2", "day": "$3", "week": "-2", "hour": "-6", "minute": "+20", "second": "-30"}, result: tConv("2021-08-15T09:14:52Z")},
+		{m: map[string]string{"weekday": "1", "hour": " 2 "}, result: tConv("2020-06-08T02:55:22Z")},
+		{m: map[string]string{"weekday": "1,3"}, result: tConv("2020-06-15T14:55:22Z")},
+		{m: map[string]string{}, result: src},
+		{m: map[string]string{"years": "+1"}, errorExpected: true},
+		{m: map[string]string{"year": "+1 M2"}, errorExpected: true},
+		{m: map[string]string{"year": ""}, errorExpected: true},
+		{m: map[string]string{"month": "0"}, errorExpected: true},
+		{m: map[string]string{"weekday": "7"}, errorExpected: true},
+	}
+
+	for i, p := range list {
+		ts, err := NewFromMap(p.m, false)
+
+		if p.errorExpected {
+			if err == nil {
+				t.Errorf(`[%d] %v prepared without error, expected error`, i, p.m)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf(`[%d] %v prepared with error: %s`, i, p.m, err)
+			continue
+		}
+
+		if r := ts.Exec(src); r != p.result {
+			t.Errorf(`[%d] %v: got "%s", expected "%s"`, i, p.m, r, p.result)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExecManyParallel(t *testing.T) {
+	ts, err := New("D+1 h-3", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := make([]time.Time, 1001)
+	for i := range in {
+		in[i] = tConv("2021-03-10T14:55:22Z").Add(time.Duration(i) * time.Hour)
+	}
+
+	serial := ts.ExecMany(in)
+
+	for _, workers := range []int{0, 1, 3, 8, 2000} {
+		out := ts.ExecManyParallel(in, workers)
+		if len(out) != len(in) {
+			t.Fatalf(`%d workers: got %d elements, expected %d`, workers, len(out), len(in))
+		}
+
+		for i := range out {
+			if out[i] != serial[i] {
+				t.Errorf(`%d workers: [%d] got "%s", expected "%s"`, workers, i, out[i], serial[i])
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkExecMany(b *testing.B) {
+	benchmarkExecMany(b, 0)
+}
+
+func BenchmarkExecManyParallel(b *testing.B) {
+	benchmarkExecMany(b, 4)
+}
+
+func benchmarkExecMany(b *testing.B, workers int) {
+	ts, err := New("Y+1 M+2 D$3 W-2 h-6 m+20 s-30", false)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	in := make([]time.Time, 100000)
+	for i := range in {
+		in[i] = tConv("2021-03-10T14:55:22Z").Add(time.Duration(i) * time.Minute)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if workers == 0 {
+			ts.ExecMany(in)
+		} else {
+			ts.ExecManyParallel(in, workers)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestTokens(t *testing.T) {
+	list := Tokens()
+	order := TokenOrder()
+
+	if len(list) != len(order) {
+		t.Fatalf(`got %d tokens, expected %d`, len(list), len(order))
+	}
+
+	for i, info := range list {
+		if info.Letter != order[i] {
+			t.Errorf(`[%d] got "%c", expected "%c"`, i, info.Letter, order[i])
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestAliases(t *testing.T) {
+	if err := RegisterAlias("test_eod", "h18 m0 s0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RegisterAlias("test_next_eod", "D+1 @test_eod"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"", "bad name", "@x"} {
+		if err := RegisterAlias(name, "D1"); err == nil {
+			t.Errorf(`"%s": error expected`, name)
+		}
+	}
+
+	if err := RegisterAlias("test_bad", "D1 D2"); err == nil {
+		t.Error(`"D1 D2": error expected`)
+	}
+
+	list := []struct {
+		pattern       string
+		errorExpected bool
+		result        time.Time
+	}{
+		{pattern: "@test_eod", result: tConv("2021-03-10T18:00:00Z")},
+		{pattern: "D+1 @test_eod", result: tConv("2021-03-11T18:00:00Z")},
+		{pattern: "  @test_next_eod", result: tConv("2021-03-11T18:00:00Z")},
+		{pattern: "D+1@test_eod", result: tConv("2021-03-11T18:00:00Z")},
+		{pattern: "@test_unknown", errorExpected: true},
+		{pattern: "@", errorExpected: true},
+		{pattern: "@test_bad", errorExpected: true},
+		{pattern: "@test_eod h1", errorExpected: true},
+	}
+
+	src := tConv("2021-03-10T14:55:22Z")
+
+	for i, p := range list {
+		ts, err := New(p.pattern, true)
+		if err != nil {
+			if !p.errorExpected {
+				t.Errorf(`[%d] "%s": %s`, i, p.pattern, err)
+			}
+			continue
+		}
+
+		if p.errorExpected {
+			t.Errorf(`[%d] "%s": error expected`, i, p.pattern)
+			continue
+		}
+
+		if r := ts.Exec(src); !r.Equal(p.result) {
+			t.Errorf(`[%d] "%s": got "%s", expected "%s"`, i, p.pattern, r, p.result)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestString(t *testing.T) {
+	list := []struct {
+		pattern  string
+		expected string
+	}{
+		{pattern: "", expected: ""},
+		{pattern: "  Y+1M-2   D$3 W^2 w5 ", expected: "Y+1 M-2 D$3 W^2 w5"},
+		{pattern: "Y2021 M+0 D!15 h-6 m20 s+30 l0 u+1 n-1", expected: "Y2021 M+0 D!15 h-6 m20 s+30 l0 u+1 n-1"},
+		{pattern: "D1 S+3661", expected: "D1 S+3661"},
+		{pattern: "w6,0 h9", expected: "w0,6 h9"},
+		{pattern: "R2MO h9", expected: "W^2 w1 h9"},
+		{pattern: "R-1FR", expected: "W$1 w5"},
+	}
+
+	for i, p := range list {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		s := ts.String()
+		if s != p.expected {
+			t.Errorf(`[%d] "%s": got "%s", expected "%s"`, i, p.pattern, s, p.expected)
+			continue
+		}
+
+		ts2, err := New(s, false)
+		if err != nil {
+			t.Errorf(`[%d] "%s": %s`, i, s, err)
+			continue
+		}
+
+		if *ts2 != *ts {
+			t.Errorf(`[%d] "%s": the parsed String() differs`, i, p.pattern)
+		}
+	}
+
+	if s := (*TimeShift)(nil).String(); s != "" {
+		t.Errorf(`nil: got "%s"`, s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestNormalizeCacheKey(t *testing.T) {
+	list := []struct {
+		pattern  string
+		expected string
+	}{
+		{pattern: "Y+1 M+2", expected: "Y+1 M+2"},
+		{pattern: "Y+1M+2", expected: "Y+1 M+2"},
+		{pattern: "Y+1 \t  M+2", expected: "Y+1 M+2"},
+		{pattern: "Y+1 2", expected: "Y+1 2"},
+		{pattern: "Y + 1", expected: "Y + 1"},
+	}
+
+	for i, p := range list {
+		if s := normalizeSpaces(p.pattern); s != p.expected {
+			t.Errorf(`[%d] "%s": got "%s", expected "%s"`, i, p.pattern, s, p.expected)
+		}
+	}
+
+	opts := &Options{NormalizeCacheKey: true}
+
+	ts1, err := NewWithOptions("Y+1 D$2  h3", true, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts2, err := NewWithOptions("Y+1D$2 h3", true, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ts1 != ts2 {
+		t.Error("the same cached object expected")
+	}
+
+	ts3, err := NewWithOptions("Y+1D$2 h3", true, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ts1 == ts3 {
+		t.Error("different cached objects expected")
+	}
+
+	if _, err = NewWithOptions("Y+1 2", true, opts); err == nil {
+		t.Error(`"Y+1 2": error expected`)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestSnapshot(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	src := time.Date(2021, 3, 10, 14, 55, 22, 123456789, time.UTC)
+	ts := Snapshot(src)
+
+	for _, tt := range []time.Time{tConv("2020-06-13T01:02:03Z"), time.Time{}, src} {
+		if r := ts.Exec(tt); r != src {
+			t.Errorf(`on "%s": got "%s", expected "%s"`, tt, r, src)
+		}
+	}
+
+	if r, expected := ts.Exec(time.Date(2000, 1, 1, 0, 0, 0, 0, ny)), time.Date(2021, 3, 10, 14, 55, 22, 123456789, ny); r != expected {
+		t.Errorf(`got "%s", expected "%s"`, r, expected)
+	}
+
+	s := ts.String()
+	if s != "Y2021 M3 D10 h14 m55 s22 l123 u456 n789" {
+		t.Errorf(`got "%s"`, s)
+	}
+
+	ts2, err := New(s, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *ts2 != *ts {
+		t.Errorf(`the parsed "%s" differs`, s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestWarmCache(t *testing.T) {
+	if err := WarmCache([]string{"Y+1 D$1 test_warm", "Y+2 D$1 h0", "Y+3 D$1 h0", "M0"}); err == nil {
+		t.Error("error expected")
+	}
+
+	if err := WarmCache([]string{"Y+2 D$1 h0", "Y+3 D$1 h0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pattern := range []string{"Y+2 D$1 h0", "Y+3 D$1 h0"} {
+		cacheMutex.RLock()
+		ts := cache[cacheKey{pattern: pattern}]
+		cacheMutex.RUnlock()
+
+		if ts == nil {
+			t.Errorf(`"%s" is not cached`, pattern)
+		}
+	}
+}
+
+func TestWarmAndVerify(t *testing.T) {
+	ref := tConv("2021-02-10T14:55:22Z")
+
+	if err := WarmAndVerify([]string{"D$1 h0", "M+1 D15 h9", "W^2 w1"}, ref); err != nil {
+		t.Fatal(err)
+	}
+
+	err := WarmAndVerify([]string{"D$1 h0", "D30 h9", "h24", "M0"}, ref)
+	if err == nil || !strings.Contains(err.Error(), `pattern 1 "D30 h9"`) {
+		t.Errorf(`got "%v", expected the error for "D30 h9"`, err)
+	}
+
+	cacheMutex.RLock()
+	ts := cache[cacheKey{pattern: "h24"}]
+	cacheMutex.RUnlock()
+
+	if ts == nil {
+		t.Errorf(`"h24" is not cached`)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestNewUncached(t *testing.T) {
+	pattern := "Y+1 M+2 D+3"
+
+	cached, err := New(pattern, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cacheMutex.RLock()
+	n := len(cache)
+	cacheMutex.RUnlock()
+
+	ts, err := NewUncached(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ts == cached {
+		t.Error("the cached object is returned")
+	}
+
+	if _, err = NewUncached("Y+1 M+2 D+4"); err != nil {
+		t.Fatal(err)
+	}
+
+	cacheMutex.RLock()
+	m := len(cache)
+	cacheMutex.RUnlock()
+
+	if n != m {
+		t.Errorf("the cache size is changed from %d to %d", n, m)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestNewRequired(t *testing.T) {
+	if err := RegisterAlias("test_empty", " "); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pattern := range []string{"", " \t\n ", "@test_empty"} {
+		ts, err := NewRequired(pattern, true)
+		if err == nil || ts != nil {
+			t.Errorf(`"%s": error expected`, pattern)
+		}
+	}
+
+	if _, err := NewRequired("Y1 Y2", false); err == nil {
+		t.Error(`"Y1 Y2": error expected`)
+	}
+
+	ts, err := NewRequired(" D+1 ", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := ts.String(); s != "D+1" {
+		t.Errorf(`got "%s"`, s)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestClock(t *testing.T) {
+	now := tConv("2021-03-10T14:55:22+03:00")
+
+	ts, err := NewWithOptions("D+1 h0", false, &Options{Clock: func() time.Time { return now }})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r, expected := ts.ExecNow(), tConv("2021-03-11T00:55:22+03:00"); r != expected {
+		t.Errorf(`got "%s", expected "%s"`, r, expected)
+	}
+
+	if r, expected := ts.ExecNowUTC(), tConv("2021-03-11T00:55:22Z"); r != expected {
+		t.Errorf(`got "%s", expected "%s"`, r, expected)
+	}
+
+	if r, expected := ts.Exec(tConv("2020-01-01T10:20:30Z")), tConv("2020-01-02T00:20:30Z"); r != expected {
+		t.Errorf(`got "%s", expected "%s"`, r, expected)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestValidateAll(t *testing.T) {
+	list := []struct {
+		pattern string
+		offsets []int
+	}{
+		{pattern: "", offsets: nil},
+		{pattern: " Y+1 M2 D$1 ", offsets: nil},
+		{pattern: "Y+1 M0 D^3 x h1", offsets: []int{4, 7, 11}},
+		{pattern: "M1 Y1 Y2", offsets: []int{3, 6}},
+		{pattern: "w7 h99999999999", offsets: []int{0, 3}},
+		{pattern: "Y1 M2 ?", offsets: []int{6}},
+		{pattern: "h1 S0", offsets: []int{-1}},
+		{pattern: "@test_unknown_validate", offsets: []int{-1}},
+	}
+
+	for i, p := range list {
+		errs := ValidateAll(p.pattern)
+
+		_, err := New(p.pattern, false)
+		if (err == nil) != (len(errs) == 0) {
+			t.Errorf(`[%d] "%s": New gives %v, ValidateAll gives %v`, i, p.pattern, err, errs)
+		}
+
+		if len(errs) != len(p.offsets) {
+			t.Errorf(`[%d] "%s": got %v, expected %d errors`, i, p.pattern, errs, len(p.offsets))
+			continue
+		}
+
+		for j, e := range errs {
+			var pe *PatternError
+			if !errors.As(e, &pe) {
+				t.Errorf(`[%d.%d] "%s": PatternError expected, got %T`, i, j, p.pattern, e)
+				continue
+			}
+
+			if pe.Offset != p.offsets[j] {
+				t.Errorf(`[%d.%d] "%s": got offset %d (%s), expected %d`, i, j, p.pattern, pe.Offset, e, p.offsets[j])
+			}
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestDaysInMonth(t *testing.T) {
+	list := []struct {
+		year     int
+		month    time.Month
+		expected int
+	}{
+		{year: 2021, month: time.January, expected: 31},
+		{year: 2021, month: time.February, expected: 28},
+		{year: 2020, month: time.February, expected: 29},
+		{year: 1900, month: time.February, expected: 28},
+		{year: 2000, month: time.February, expected: 29},
+		{year: 0, month: time.February, expected: 29},
+		{year: 2021, month: time.April, expected: 30},
+		{year: 2021, month: 14, expected: 28},
+	}
+
+	for i, p := range list {
+		if n := DaysInMonth(p.year, p.month); n != p.expected {
+			t.Errorf(`[%d] %d-%d: got %d, expected %d`, i, p.year, p.month, n, p.expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestAndThen(t *testing.T) {
+	base, err := New("M2 D+30", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := base.AndThen("D$1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err = ts.AndThen("w5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := tConv("2021-01-15T14:55:22Z")
+
+	// M2 D+30 gives Mar 17, D$1 gives Mar 31 (Wednesday), w5 gives Apr 2
+	if r, expected := ts.Exec(src), tConv("2021-04-02T14:55:22Z"); r != expected {
+		t.Errorf(`got "%s", expected "%s"`, r, expected)
+	}
+
+	if r, expected := base.Exec(src), tConv("2021-03-17T14:55:22Z"); r != expected {
+		t.Errorf(`the source is changed: got "%s", expected "%s"`, r, expected)
+	}
+
+	if _, err = base.AndThen("D1 D2"); err == nil {
+		t.Error("error expected")
+	}
+
+	if c, _ := base.AndThen(" "); c != base {
+		t.Error("the empty stage must give the same shift")
+	}
+
+	empty, _ := New("", false)
+	if c, _ := empty.AndThen("D$1"); c.String() != "D$1" {
+		t.Errorf(`got "%s", expected "D$1"`, c.String())
+	}
+
+	rf, err := NewWithOptions("M2 D+30", false, &Options{RelativeFirst: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rf, err = rf.AndThen("h-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r, expected := rf.Exec(src), tConv("2021-02-14T13:55:22Z"); r != expected {
+		t.Errorf(`got "%s", expected "%s"`, r, expected)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestFormatPattern(t *testing.T) {
+	list := []struct {
+		pattern  string
+		expected string
+	}{
+		{pattern: "", expected: ""},
+		{pattern: "Y+1M-2   D$3", expected: "Y+1    M-2  D$3"},
+		{pattern: "Y2021 M12 D1", expected: "Y2021  M12  D1"},
+		{pattern: "Y2021 h9", expected: "Y2021  h9"},
+		{pattern: "M+1 W^2 w5", expected: "M+1  W^2  w5"},
+		{pattern: "w0,6 h9", expected: "w0,6 h9"},
+		{pattern: "m+123456 s1", expected: "m+123456 s1"},
+	}
+
+	if len(formatWidths) != len(tokenOrder) {
+		t.Fatalf("%d widths for %d parts", len(formatWidths), len(tokenOrder))
+	}
+
+	for i, p := range list {
+		s, err := FormatPattern(p.pattern)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		if s != p.expected {
+			t.Errorf(`[%d] "%s": got "%s", expected "%s"`, i, p.pattern, s, p.expected)
+		}
+
+		a, _ := New(p.pattern, false)
+		b, err := New(s, false)
+		if err != nil || Compare(a, b) != 0 {
+			t.Errorf(`[%d] "%s": "%s" is parsed to another shift (%v)`, i, p.pattern, s, err)
+		}
+	}
+
+	if _, err := FormatPattern("Y1 Y2"); err == nil {
+		t.Error("error expected")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExecOrInput(t *testing.T) {
+	src := tConv("2021-01-31T14:55:22Z")
+
+	list := []struct {
+		pattern       string
+		options       *Options
+		errorExpected bool
+		result        time.Time
+	}{
+		{pattern: "", result: src},
+		{pattern: "M+1 D$1", result: tConv("2021-03-03T14:55:22Z")},
+		{pattern: "M+1 D$1", options: &Options{ClampDay: true}, result: tConv("2021-02-28T14:55:22Z")},
+		{pattern: "M2 D28 h23 m59 s59 l999 u999 n999", result: tConv("2021-02-28T23:59:59.999999999Z")},
+		{pattern: "M+1 D29", errorExpected: true},
+		{pattern: "Y2024 M2 D29", result: tConv("2024-02-29T14:55:22Z")},
+		{pattern: "M2 D$29", errorExpected: true},
+		{pattern: "M2 D$28", options: &Options{FromEndZeroBased: true}, errorExpected: true},
+		{pattern: "M2 D31", options: &Options{ClampDay: true}, result: tConv("2021-02-28T14:55:22Z")},
+		{pattern: "M13", errorExpected: true},
+		{pattern: "h24", errorExpected: true},
+		{pattern: "h24", options: &Options{WrapTimeOfDay: true}, result: tConv("2021-01-31T00:55:22Z")},
+		{pattern: "m60", errorExpected: true},
+		{pattern: "s60", options: &Options{WrapMinuteSecond: true}, result: tConv("2021-01-31T14:55:00Z")},
+		{pattern: "S86400", errorExpected: true},
+		{pattern: "l1000", errorExpected: true},
+		{pattern: "M+13 h+25", result: tConv("2022-03-04T15:55:22Z")},
+	}
+
+	for i, p := range list {
+		ts, err := NewWithOptions(p.pattern, false, p.options)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		r, err := ts.ExecChecked(src)
+		if (err != nil) != p.errorExpected {
+			t.Errorf(`[%d] "%s": got error %v, expected %v`, i, p.pattern, err, p.errorExpected)
+			continue
+		}
+
+		expected := p.result
+		if p.errorExpected {
+			expected = src
+		}
+
+		if r != expected {
+			t.Errorf(`[%d] "%s": ExecChecked gives "%s", expected "%s"`, i, p.pattern, r, expected)
+		}
+
+		if r := ts.ExecOrInput(src); r != expected {
+			t.Errorf(`[%d] "%s": ExecOrInput gives "%s", expected "%s"`, i, p.pattern, r, expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestCompile(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	patterns := []string{"", "Y+1 M+2 D+3 h-6 m+20 s-30", "M+1", "D-1 K+2", "D-400 h+25 l+999 u+999 n+999", "n-1", "D$1 h-1", "W^2 w1", "S+1", "Y2021 M+1"}
+	sources := []time.Time{
+		tConv("2020-06-13T14:55:22.123456789Z"),
+		tConv("2021-01-31T00:00:00Z"),
+		time.Date(2021, 3, 13, 2, 30, 0, 0, ny),
+		time.Date(2021, 11, 7, 1, 30, 0, 0, ny),
+	}
+
+	for _, pattern := range patterns {
+		for _, o := range []*Options{nil, {PreserveMonthEnd: true}} {
+			ts, err := NewWithOptions(pattern, false, o)
+			if err != nil {
+				t.Fatalf(`"%s": %s`, pattern, err)
+			}
+
+			f := ts.Compile()
+
+			for _, src := range sources {
+				if r, expected := f(src), ts.Exec(src); r != expected {
+					t.Errorf(`"%s" on "%s": got "%s", expected "%s"`, pattern, src, r, expected)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkExec(b *testing.B) {
+	benchmarkCompile(b, false)
+}
+
+func BenchmarkCompile(b *testing.B) {
+	benchmarkCompile(b, true)
+}
+
+func benchmarkCompile(b *testing.B, compiled bool) {
+	ts, err := New("Y+1 M+2 D+3 h-6 m+20 s-30", false)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	f := ts.Exec
+	if compiled {
+		f = ts.Compile()
+	}
+
+	t := tConv("2020-06-13T14:55:22Z")
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		t = f(t)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestNewFromGoDuration(t *testing.T) {
+	params := []struct {
+		s        string
+		expected string
+		isErr    bool
+	}{
+		{"1h30m", "h+1 m+30", false},
+		{"-1.5s", "s-1 l-500", false},
+		{"36h1us2ns", "h+36 u+1 n+2", false},
+		{"0s", "", false},
+		{"1d", "", true},
+		{"", "", true},
+	}
+
+	for i, p := range params {
+		ts, err := NewFromGoDuration(p.s, false)
+		if err != nil {
+			if !p.isErr {
+				t.Errorf(`[%d] "%s": unexpected error: %s`, i, p.s, err)
+			}
+			continue
+		}
+
+		if p.isErr {
+			t.Errorf(`[%d] "%s": error expected`, i, p.s)
+			continue
+		}
+
+		if s := ts.String(); s != p.expected {
+			t.Errorf(`[%d] "%s": got "%s", expected "%s"`, i, p.s, s, p.expected)
+		}
+	}
+
+	d := -(36*time.Hour + 1234567891)
+	ts, err := NewFromGoDuration(d.String(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := tConv("2020-06-13T14:55:22Z")
+	if r, expected := ts.Exec(src), src.Add(d); !r.Equal(expected) {
+		t.Errorf(`got "%s", expected "%s"`, r, expected)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestGrammarJSON(t *testing.T) {
+	var doc struct {
+		Order  string `json:"order"`
+		Tokens []struct {
+			Letter  string `json:"letter"`
+			Max     int    `json:"max"`
+			Options string `json:"options"`
+		} `json:"tokens"`
+	}
+
+	if err := json.Unmarshal(GrammarJSON(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Order != string(TokenOrder()) {
+		t.Errorf(`got order "%s", expected "%s"`, doc.Order, TokenOrder())
+	}
+
+	if len(doc.Tokens) != len(Tokens()) {
+		t.Fatalf(`got %d tokens, expected %d`, len(doc.Tokens), len(Tokens()))
+	}
+
+	for i, tk := range doc.Tokens {
+		if tk.Letter != string(doc.Order[i]) {
+			t.Errorf(`[%d] got "%s", expected "%c"`, i, tk.Letter, doc.Order[i])
+		}
+	}
+
+	if y := doc.Tokens[0]; y.Max != 9999 || y.Options != "" {
+		t.Errorf(`Y: got max %d and options "%s"`, y.Max, y.Options)
+	}
+
+	if d := doc.Tokens[2]; d.Options != "$!" {
+		t.Errorf(`D: got options "%s", expected "$!"`, d.Options)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestPeriod(t *testing.T) {
+	const day = 24 * time.Hour
+
+	params := []struct {
+		pattern string
+		period  time.Duration
+		ok      bool
+	}{
+		{"", 0, false},
+		{"h9 m30 s0", day, true},
+		{"S3600", day, true},
+		{"w1 h9", 7 * day, true},
+		{"d5", 7 * day, true},
+		{"D1 h0", 2629746 * time.Second, true},
+		{"W$1 w5", 2629746 * time.Second, true},
+		{"M12 D25", 31556952 * time.Second, true},
+		{"W10", 31556952 * time.Second, true},
+		{"Y2021 M1", 0, false},
+		{"h+1", 0, false},
+		{"D1 h+9", 0, false},
+		{"w0,6", 0, false},
+	}
+
+	for i, p := range params {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		period, ok := ts.Period()
+		if period != p.period || ok != p.ok {
+			t.Errorf(`[%d] "%s": got %s %v, expected %s %v`, i, p.pattern, period, ok, p.period, p.ok)
+		}
+	}
+
+	ts, _ := New("h9", false)
+	ts, err := ts.AndThen("m30")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := ts.Period(); ok {
+		t.Errorf("AndThen: ok is not expected")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExecNull(t *testing.T) {
+	ts, err := New("D+1 h9", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := ts.ExecNull(sql.NullTime{}); r.Valid || !r.Time.IsZero() {
+		t.Errorf(`got %v, expected invalid`, r)
+	}
+
+	src := tConv("2020-06-13T14:55:22Z")
+	r := ts.ExecNull(sql.NullTime{Time: src, Valid: true})
+	if expected := tConv("2020-06-14T09:55:22Z"); !r.Valid || !r.Time.Equal(expected) {
+		t.Errorf(`got %v, expected "%s"`, r, expected)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestWeekFromEndPartialWeek(t *testing.T) {
+	// the months of 2021 ending on Sunday, Monday, ..., Saturday
+	months := []time.Month{time.October, time.May, time.August, time.March, time.September, time.April, time.July}
+
+	for i, month := range months {
+		src := time.Date(2021, month, 10, 14, 55, 22, 0, time.UTC)
+		last := time.Date(2021, month+1, 0, 14, 55, 22, 0, time.UTC)
+		if int(last.Weekday()) != i {
+			t.Fatalf(`%s ends on %s`, month, last.Weekday())
+		}
+
+		for wd := 0; wd < 7; wd++ {
+			for _, pattern := range []string{"W$1 w%d", "W^1 w%d"} {
+				pattern = fmt.Sprintf(pattern, wd)
+
+				ts, err := New(pattern, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				expected := last.AddDate(0, 0, -(i-wd+7)%7)
+				if pattern[1] == '^' {
+					expected = time.Date(2021, month, 1+(wd-int(time.Date(2021, month, 1, 0, 0, 0, 0, time.UTC).Weekday())+7)%7, 14, 55, 22, 0, time.UTC)
+				}
+
+				if r := ts.Exec(src); !r.Equal(expected) {
+					t.Errorf(`%s "%s": got "%s", expected "%s"`, month, pattern, r, expected)
+				}
+			}
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestSpec(t *testing.T) {
+	for _, pattern := range []string{"", "Y+1 M2 D$1 h0", "M^+1 D!15", "W$2 w5 S+10 l1 u2 n-3", "h-3", "w1,3,5 h9", "W+1 d4", "K-2 w1"} {
+		o := &Options{AllowMonthSnap: true}
+		ts, err := NewWithOptions(pattern, false, o)
+		if err != nil {
+			t.Fatalf(`"%s": %s`, pattern, err)
+		}
+
+		spec := ts.Spec()
+
+		if pattern == "M^+1 D!15" {
+			if !spec.Month.FromBegin || !spec.Month.Relative || spec.Month.Value != 1 || !spec.Day.Nearest {
+				t.Errorf(`"%s": got %+v`, pattern, spec)
+			}
+		}
+
+		if spec.Options != o {
+			t.Errorf(`"%s": options are not kept`, pattern)
+		}
+
+		built, err := spec.Build()
+		if err != nil {
+			t.Fatalf(`"%s": %s`, pattern, err)
+		}
+
+		if s := built.String(); s != pattern {
+			t.Errorf(`got "%s", expected "%s"`, s, pattern)
+		}
+
+		if built.options != ts.options {
+			t.Errorf(`"%s": got options %+v, expected %+v`, pattern, built.options, ts.options)
+		}
+	}
+
+	spec := Spec{Month: SpecPart{Active: true, Value: 1, Relative: true, FromBegin: true}}
+	if _, err := spec.Build(); err == nil {
+		t.Error(`"M^+1" without AllowMonthSnap: error expected`)
+	}
+
+	spec = Spec{Weekday: SpecPart{Active: true, Weekdays: []int{6, 0}}}
+	ts, err := spec.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := ts.String(); s != "w0,6" {
+		t.Errorf(`got "%s", expected "w0,6"`, s)
+	}
+
+	for i, spec := range []Spec{
+		{Day: SpecPart{Active: true, Value: -1}},
+		{Day: SpecPart{Active: true, Value: 1, Weekdays: []int{1}}},
+		{Weekday: SpecPart{Active: true, Weekdays: []int{1, 1}}},
+		{Weekday: SpecPart{Active: true, Weekdays: []int{7}}},
+		{Month: SpecPart{Active: true, Value: 0}},
+		{Hour: SpecPart{Active: true, Value: 1, Nearest: true}},
+	} {
+		if _, err := spec.Build(); err == nil {
+			t.Errorf(`[%d] error expected`, i)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExecP(t *testing.T) {
+	ts, err := New("D+1 h9", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts.ExecP(nil)
+
+	tt := tConv("2020-06-13T14:55:22Z")
+	ts.ExecP(&tt)
+	if expected := tConv("2020-06-14T09:55:22Z"); !tt.Equal(expected) {
+		t.Errorf(`got "%s", expected "%s"`, tt, expected)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExecWhen(t *testing.T) {
+	ts, err := New("w1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := []struct {
+		src      time.Time
+		expected time.Time
+	}{
+		{tConv("2020-06-13T14:55:22Z"), tConv("2020-06-08T14:55:22Z")}, // Saturday
+		{tConv("2020-06-14T14:55:22Z"), tConv("2020-06-15T14:55:22Z")}, // Sunday
+		{tConv("2020-06-10T14:55:22Z"), tConv("2020-06-10T14:55:22Z")}, // Wednesday
+	}
+
+	for i, p := range params {
+		if r := ts.ExecWhen(p.src, isWeekend); !r.Equal(p.expected) {
+			t.Errorf(`[%d] got "%s", expected "%s"`, i, r, p.expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestProlepticGregorian(t *testing.T) {
+	params := []struct {
+		pattern  string
+		src      time.Time
+		expected time.Time
+	}{
+		{"D+1", time.Date(1582, 10, 4, 12, 0, 0, 0, time.UTC), time.Date(1582, 10, 5, 12, 0, 0, 0, time.UTC)},
+		{"D+10", time.Date(1582, 10, 5, 12, 0, 0, 0, time.UTC), time.Date(1582, 10, 15, 12, 0, 0, 0, time.UTC)},
+		{"Y1500 M2 D$1", time.Date(1600, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(1500, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{"Y1500 M2 D29", time.Date(1600, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(1500, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"Y+100 M2 D$1", time.Date(1500, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(1600, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"Y1582 W1 w5", time.Date(1600, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(1582, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for i, p := range params {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		if r := ts.Exec(p.src); !r.Equal(p.expected) {
+			t.Errorf(`[%d] "%s": got "%s", expected "%s"`, i, p.pattern, r, p.expected)
+		}
+	}
+
+	// the relative shifts are the same as the time.Date normalization for every day of 1500-1582
+	ts, err := New("Y+1 M+1 D+1 h+25", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for src := time.Date(1500, 1, 1, 10, 0, 0, 0, time.UTC); src.Year() <= 1582; src = src.AddDate(0, 0, 1) {
+		year, month, day := src.Date()
+		if r, expected := ts.Exec(src), time.Date(year+1, month+1, day+1, 10+25, 0, 0, 0, time.UTC); !r.Equal(expected) {
+			t.Fatalf(`"%s": got "%s", expected "%s"`, src, r, expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExecJSONtz(t *testing.T) {
+	ts, err := New("D+1 h9", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s, expected := ts.ExecJSONtz(tConv("2020-06-13T14:55:22Z")), "2020-06-14T09:55:22.000Z"; s != expected {
+		t.Errorf(`got "%s", expected "%s"`, s, expected)
+	}
+
+	if s, expected := ts.ExecJSONtz(tConv("2020-06-13T14:55:22.123+03:00")), "2020-06-14T09:55:22.123+03:00"; s != expected {
+		t.Errorf(`got "%s", expected "%s"`, s, expected)
+	}
+
+	for _, p := range testParameters {
+		if p.errorExpected {
+			continue
+		}
+
+		if s, expected := ts.ExecJSONtz(p.t), misc.Time2JSONtz(ts.Exec(p.t)); s != expected {
+			t.Errorf(`got "%s", expected "%s"`, s, expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestDiffTo(t *testing.T) {
+	from := tConv("2020-06-13T14:55:22.123456789Z")
+	to := tConv("2021-02-03T06:20:30.987654321+03:00")
+
+	params := []struct {
+		finest   byte
+		pattern  string
+		expected time.Time
+	}{
+		{'Y', "Y2021", tConv("2021-06-13T14:55:22.123456789Z")},
+		{'D', "Y2021 M2 D3", tConv("2021-02-03T14:55:22.123456789Z")},
+		{'h', "Y2021 M2 D3 h3", tConv("2021-02-03T03:55:22.123456789Z")},
+		{'s', "Y2021 M2 D3 h3 m20 s30", tConv("2021-02-03T03:20:30.123456789Z")},
+		{'u', "Y2021 M2 D3 h3 m20 s30 l987 u654", tConv("2021-02-03T03:20:30.987654789Z")},
+		{'n', "Y2021 M2 D3 h3 m20 s30 l987 u654 n321", to},
+	}
+
+	for _, p := range params {
+		ts := DiffTo(from, to, p.finest)
+
+		if s := ts.String(); s != p.pattern {
+			t.Errorf(`%c: got "%s", expected "%s"`, p.finest, s, p.pattern)
+		}
+
+		if r := ts.Exec(from); !r.Equal(p.expected) {
+			t.Errorf(`%c: got "%s", expected "%s"`, p.finest, r, p.expected)
+		}
+	}
+
+	if ts := DiffTo(from, to, 'W'); ts != nil {
+		t.Errorf(`W: got "%s", expected nil`, ts)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestStartOfNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	src := time.Date(2021, 3, 13, 14, 55, 22, 123, ny) // Saturday, DST starts on Mar 14
+
+	if r, expected := StartOfNextDay(src), time.Date(2021, 3, 14, 0, 0, 0, 0, ny); !r.Equal(expected) || r.Location() != ny {
+		t.Errorf(`day: got "%s", expected "%s"`, r, expected)
+	}
+
+	if r, expected := StartOfNextDay(tConv("2020-12-31T23:59:59Z")), tConv("2021-01-01T00:00:00Z"); !r.Equal(expected) {
+		t.Errorf(`day: got "%s", expected "%s"`, r, expected)
+	}
+
+	params := []struct {
+		weekStart time.Weekday
+		expected  time.Time
+	}{
+		{time.Sunday, time.Date(2021, 3, 14, 0, 0, 0, 0, ny)},
+		{time.Monday, time.Date(2021, 3, 15, 0, 0, 0, 0, ny)},
+		{time.Saturday, time.Date(2021, 3, 20, 0, 0, 0, 0, ny)},
+	}
+
+	for _, p := range params {
+		if r := StartOfNextWeek(src, p.weekStart); !r.Equal(p.expected) {
+			t.Errorf(`week from %s: got "%s", expected "%s"`, p.weekStart, r, p.expected)
+		}
+	}
+
+	if r, expected := StartOfNextMonth(src), time.Date(2021, 4, 1, 0, 0, 0, 0, ny); !r.Equal(expected) || r.Location() != ny {
+		t.Errorf(`month: got "%s", expected "%s"`, r, expected)
+	}
+
+	if r, expected := StartOfNextMonth(tConv("2020-12-31T23:59:59Z")), tConv("2021-01-01T00:00:00Z"); !r.Equal(expected) {
+		t.Errorf(`month: got "%s", expected "%s"`, r, expected)
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestClampWeekBoundaryMonths(t *testing.T) {
+	// February 2021 has exactly 4 occurrences of every weekday, March 2021 has 5 for Mon, Tue and Wed
+	months := []time.Month{time.February, time.March}
+
+	for _, month := range months {
+		src := time.Date(2021, month, 10, 14, 55, 22, 0, time.UTC)
+		first := time.Date(2021, month, 1, 14, 55, 22, 0, time.UTC)
+		last := time.Date(2021, month+1, 0, 14, 55, 22, 0, time.UTC)
+
+		for wd := 0; wd < 7; wd++ {
+			firstX := first.AddDate(0, 0, (wd-int(first.Weekday())+7)%7)
+			lastX := last.AddDate(0, 0, -(int(last.Weekday())-wd+7)%7)
+			has5 := lastX.Sub(firstX) == 28*24*time.Hour
+
+			for _, pattern := range []string{"W^5 w%d", "W$5 w%d"} {
+				pattern = fmt.Sprintf(pattern, wd)
+
+				spill, err := New(pattern, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				clamp, err := NewWithOptions(pattern, false, &Options{ClampWeek: true})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				r := spill.Exec(src)
+				rc := clamp.Exec(src)
+
+				if (r.Month() == month) != has5 {
+					t.Errorf(`%s "%s": got "%s", in the month: %v`, month, pattern, r, has5)
+				}
+
+				// the last occurrence for W^, the first one for W$
+				expected := lastX
+				if pattern[1] == '$' {
+					expected = firstX
+				}
+				if has5 {
+					expected = r
+				}
+
+				if !rc.Equal(expected) {
+					t.Errorf(`%s "%s" with ClampWeek: got "%s", expected "%s"`, month, pattern, rc, expected)
+				}
+			}
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestCron(t *testing.T) {
+	params := []struct {
+		pattern string
+		expr    string
+		ok      bool
+	}{
+		{"h9 m30", "30 9 * * *", true},
+		{"h9 m30 s0 l0 u0 n0", "30 9 * * *", true},
+		{"h9", "* 9 * * *", true},
+		{"M12 D25 h0 m0", "0 0 25 12 *", true},
+		{"w1 h9 m0", "0 9 * * 1", true},
+		{"w0,6 h10 m15", "15 10 * * 0,6", true},
+		{"", "", false},
+		{"h+1", "", false},
+		{"Y2021 h9", "", false},
+		{"W^1 w1 h9", "", false},
+		{"d1 h9", "", false},
+		{"S3600", "", false},
+		{"D$1 h0", "", false},
+		{"D!1 h9", "", false},
+		{"D1 w1", "", false},
+		{"h9 m30 s15", "", false},
+		{"h24", "", false},
+		{"M13", "", false},
+		{"D32", "", false},
+	}
+
+	for i, p := range params {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		expr, ok := ts.Cron()
+		if expr != p.expr || ok != p.ok {
+			t.Errorf(`[%d] "%s": got "%s" %v, expected "%s" %v`, i, p.pattern, expr, ok, p.expr, p.ok)
+		}
+	}
+
+	ts, err := NewWithOptions("D31 h0 m0", false, &Options{ClampDay: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ts.Cron(); ok {
+		t.Errorf("ClampDay: ok is not expected")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestNewFromCron(t *testing.T) {
+	params := []struct {
+		expr    string
+		pattern string
+		isErr   bool
+	}{
+		{"30 9 * * 1", "w1 h9 m30 s0 l0 u0 n0", false},
+		{"  0 0 25 12 *  ", "M12 D25 h0 m0 s0 l0 u0 n0", false},
+		{"* * * * 7", "w0 s0 l0 u0 n0", false},
+		{"7 17 * * 7", "w0 h17 m7 s0 l0 u0 n0", false},
+		{"* * * * *", "s0 l0 u0 n0", false},
+		{"0 9 * *", "", true},
+		{"0 9 * * * *", "", true},
+		{"*/5 * * * *", "", true},
+		{"0 9 * * 1-5", "", true},
+		{"0 9 * * 0,6", "", true},
+		{"0 9 * JAN *", "", true},
+		{"60 9 * * *", "", true},
+		{"0 9 0 * *", "", true},
+		{"0 9 * * 8", "", true},
+		{"0 9 1 * 1", "", true},
+		{"+5 9 * * *", "", true},
+		{"0 +9 * * *", "", true},
+		{"0 9 * * +1", "", true},
+	}
+
+	for i, p := range params {
+		ts, err := NewFromCron(p.expr, false)
+		if err != nil {
+			if !p.isErr {
+				t.Errorf(`[%d] "%s": unexpected error: %s`, i, p.expr, err)
+			}
+			continue
+		}
+
+		if p.isErr {
+			t.Errorf(`[%d] "%s": error expected`, i, p.expr)
+			continue
+		}
+
+		if s := ts.String(); s != p.pattern {
+			t.Errorf(`[%d] "%s": got "%s", expected "%s"`, i, p.expr, s, p.pattern)
+		}
+
+		fields := strings.Fields(p.expr)
+		if fields[4] == "7" {
+			fields[4] = "0" // Sunday
+		}
+
+		expr, ok := ts.Cron()
+		if expected := strings.Join(fields, " "); !ok || expr != expected {
+			t.Errorf(`[%d] "%s": Cron gives "%s" %v`, i, p.expr, expr, ok)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestSplit(t *testing.T) {
+	params := []struct {
+		pattern string
+		options *Options
+		ok      bool
+	}{
+		{pattern: "", ok: true},
+		{pattern: "Y+1 M+2 D+3 h-6 m+20 s-30", ok: true},
+		{pattern: "D$1 h9", ok: true},
+		{pattern: "M^+1 h9", options: &Options{AllowMonthSnap: true}, ok: true},
+		{pattern: "W^2 w1 h9 m0", ok: true},
+		{pattern: "D1", ok: true},
+		{pattern: "S3600", ok: true},
+		{pattern: "l1 u2 n3", ok: true},
+		{pattern: "K-1 h+2", ok: true},
+		{pattern: "Y2021 M2 D29", ok: true},
+		{pattern: "D$1 h+25", options: &Options{ClampDay: true}, ok: true},
+		{pattern: "w3 h25", options: &Options{WrapTimeOfDay: true}, ok: true},
+		{pattern: "D!26 w0 m46", ok: true},
+		{pattern: "w0,6 h9", ok: true},
+		{pattern: "w3 h+25", ok: false},
+		{pattern: "K-1 w3 h+2", ok: false},
+		{pattern: "W^2 w1 m+30", ok: false},
+		{pattern: "d4 S90000", ok: false},
+		{pattern: "D$1 h+25", ok: false},
+		{pattern: "D!15 s-90", ok: false},
+		{pattern: "D15 h-25", options: &Options{BusinessDayConvention: BusinessDayFollowing}, ok: false},
+		{pattern: "D+1 h9", options: &Options{RelativeFirst: true}, ok: false},
+		{pattern: "h9", options: &Options{AbsoluteDirection: AbsoluteForward}, ok: false},
+	}
+
+	sources := []time.Time{
+		tConv("2020-06-13T14:55:22.123456789Z"),
+		tConv("2021-01-31T00:00:00Z"),
+		tConv("2021-01-02T23:30:00Z"),
+		tConv("2020-02-05T00:30:00Z"),
+		tConv("2000-03-07T11:38:20Z"),
+		tConv("2021-03-10T14:55:22Z"),
+	}
+
+	for i, p := range params {
+		ts, err := NewWithOptions(p.pattern, false, p.options)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		date, timePart, ok := ts.Split()
+		if ok != p.ok {
+			t.Errorf(`[%d] "%s": got ok=%v, expected %v`, i, p.pattern, ok, p.ok)
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		for _, src := range sources {
+			if r, expected := timePart.Exec(date.Exec(src)), ts.Exec(src); !r.Equal(expected) {
+				t.Errorf(`[%d] "%s" on "%s": got "%s", expected "%s"`, i, p.pattern, src, r, expected)
+			}
+		}
+	}
+
+	// the case ok=false is for: the date part is applied to the normalized time in Exec
+	ts, err := New("w3 h+25", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date, timePart, _ := ts.Split()
+	src := tConv("2021-01-02T00:30:00Z")
+	if r, expected := timePart.Exec(date.Exec(src)), ts.Exec(src); r.Equal(expected) {
+		t.Errorf(`"w3 h+25": got "%s", a difference expected`, r)
+	}
+
+	ts, err = New("Y+1 D$1 h9 m0", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date, timePart, _ = ts.Split()
+	if date.String() != "Y+1 D$1" || timePart.String() != "h9 m0" {
+		t.Errorf(`got "%s" and "%s"`, date, timePart)
+	}
+
+	ts, err = New("D+1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err = ts.AndThen("h9")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date, timePart, _ = ts.Split()
+	src = tConv("2020-06-13T14:55:22Z")
+	if r, expected := timePart.Exec(date.Exec(src)), tConv("2020-06-14T09:55:22Z"); !r.Equal(expected) {
+		t.Errorf(`AndThen: got "%s", expected "%s"`, r, expected)
+	}
+
+	if _, tp, ok := (*TimeShift)(nil).Split(); tp.PartCount() != 0 || !ok {
+		t.Errorf("nil: empty expected")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestBusinessDaysBetween(t *testing.T) {
+	newYear := func(t time.Time) bool {
+		return t.Month() == time.January && t.Day() == 1
+	}
+
+	params := []struct {
+		from     string
+		to       string
+		holidays func(time.Time) bool
+		expected int
+	}{
+		{"2021-03-01T10:00:00Z", "2021-03-01T15:00:00Z", nil, 0},  // the same day
+		{"2021-03-01T10:00:00Z", "2021-03-02T09:00:00Z", nil, 1},  // Mon - Tue
+		{"2021-03-05T10:00:00Z", "2021-03-08T10:00:00Z", nil, 1},  // Fri - Mon
+		{"2021-03-06T10:00:00Z", "2021-03-08T10:00:00Z", nil, 0},  // Sat - Mon
+		{"2021-03-01T00:00:00Z", "2021-04-01T00:00:00Z", nil, 23}, // March 2021
+		{"2020-12-28T00:00:00Z", "2021-01-04T00:00:00Z", nil, 5},
+		{"2020-12-28T00:00:00Z", "2021-01-04T00:00:00Z", newYear, 4},
+		{"2021-03-08T10:00:00Z", "2021-03-05T10:00:00Z", nil, -1},      // reversed
+		{"2021-03-01T23:00:00Z", "2021-03-02T02:00:00+03:00", nil, 0},  // the same day in the location of from
+		{"2021-03-16T01:00:00+03:00", "2021-03-08T12:00:00Z", nil, -6}, // reversed, Mar 16 in the location of from
+	}
+
+	for i, p := range params {
+		if n := BusinessDaysBetween(tConv(p.from), tConv(p.to), p.holidays); n != p.expected {
+			t.Errorf(`[%d] got %d, expected %d`, i, n, p.expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestRRULE(t *testing.T) {
+	params := []struct {
+		pattern string
+		rule    string
+		ok      bool
+	}{
+		{"W^2 w1 h9 m0 s0", "FREQ=MONTHLY;BYDAY=2MO;BYHOUR=9;BYMINUTE=0", true},
+		{"W$1 w5 h17", "FREQ=MONTHLY;BYDAY=-1FR;BYHOUR=17", true},
+		{"M12 D25 h0 m0", "FREQ=YEARLY;BYMONTH=12;BYMONTHDAY=25;BYHOUR=0;BYMINUTE=0", true},
+		{"D$1 h23 m59 s59", "FREQ=MONTHLY;BYMONTHDAY=-1;BYHOUR=23;BYMINUTE=59;BYSECOND=59", true},
+		{"w0,6 h10", "FREQ=WEEKLY;BYDAY=SU,SA;BYHOUR=10", true},
+		{"d7 h8", "FREQ=WEEKLY;BYDAY=SU;BYHOUR=8", true},
+		{"h9 m30 s0 l0 u0 n0", "FREQ=DAILY;BYHOUR=9;BYMINUTE=30", true},
+		{"m15", "FREQ=HOURLY;BYMINUTE=15", true},
+		{"s30", "FREQ=MINUTELY;BYSECOND=30", true},
+		{"", "", false},
+		{"D+1 h9", "", false},
+		{"Y2021 M1", "", false},
+		{"W10 w1", "", false},
+		{"W^2 h9", "", false},
+		{"W^6 w1", "", false},
+		{"S3600", "", false},
+		{"D!1 h9", "", false},
+		{"D1 w1", "", false},
+		{"h24", "", false},
+		{"D32", "", false},
+		{"h9 l1", "", false},
+		{"s0", "", false},
+		{"s0 l0 u0 n0", "", false},
+	}
+
+	for i, p := range params {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		rule, ok := ts.RRULE()
+		if rule != p.rule || ok != p.ok {
+			t.Errorf(`[%d] "%s": got "%s" %v, expected "%s" %v`, i, p.pattern, rule, ok, p.rule, p.ok)
+		}
+
+		if !ok {
+			continue
+		}
+
+		// round trip
+		back, err := NewFromRRULE(rule, false)
+		if err != nil {
+			t.Errorf(`[%d] "%s": NewFromRRULE: %s`, i, rule, err)
+			continue
+		}
+		if r, _ := back.RRULE(); r != rule {
+			t.Errorf(`[%d] "%s": got "%s" back`, i, rule, r)
+		}
+	}
+
+	ts, err := NewWithOptions("D$0", false, &Options{FromEndZeroBased: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule, ok := ts.RRULE(); !ok || rule != "FREQ=MONTHLY;BYMONTHDAY=-1" {
+		t.Errorf(`FromEndZeroBased: got "%s" %v`, rule, ok)
+	}
+
+	ts, err = NewWithOptions("W^5 w1", false, &Options{ClampWeek: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ts.RRULE(); ok {
+		t.Errorf("ClampWeek: ok is not expected")
+	}
+
+	ts, err = NewWithOptions("W^1 w1", false, &Options{WeekOfMonthRule: WeekOfMonthFull})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ts.RRULE(); ok {
+		t.Errorf("WeekOfMonthFull: ok is not expected")
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestNewFromRRULE(t *testing.T) {
+	params := []struct {
+		rrule   string
+		pattern string
+		isErr   bool
+	}{
+		{"FREQ=MONTHLY;BYDAY=2MO;BYHOUR=9;BYMINUTE=0", "W^2 w1 h9 m0", false},
+		{"RRULE:FREQ=MONTHLY;BYDAY=-1FR;BYHOUR=17", "W$1 w5 h17", false},
+		{"FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", "M11 W^4 w4", false},
+		{"FREQ=YEARLY;BYDAY=10MO", "W10 w1", false},
+		{"freq=yearly;interval=1;bymonth=12;bymonthday=25", "M12 D25", false},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1;BYHOUR=23;BYMINUTE=59;BYSECOND=59", "D$1 h23 m59 s59", false},
+		{"FREQ=WEEKLY;BYDAY=SU,SA;BYHOUR=10", "w0,6 h10", false},
+		{"FREQ=WEEKLY;BYDAY=FR,MO,WE", "w1,3,5", false},
+		{"FREQ=WEEKLY;BYDAY=MO,MO", "", true},
+		{"FREQ=WEEKLY;BYDAY=MO,TU,MO", "", true},
+		{"FREQ=WEEKLY;BYDAY=MO", "w1", false},
+		{"FREQ=HOURLY;BYMINUTE=15", "m15", false},
+		{"", "", true},
+		{"BYHOUR=9", "", true},
+		{"FREQ=SECONDLY;BYSECOND=1", "", true},
+		{"FREQ=DAILY", "", true},
+		{"FREQ=DAILY;INTERVAL=2;BYHOUR=9", "", true},
+		{"FREQ=DAILY;COUNT=10;BYHOUR=9", "", true},
+		{"FREQ=MONTHLY;BYDAY=MO;BYSETPOS=1", "", true},
+		{"FREQ=DAILY;BYHOUR=9,17", "", true},
+		{"FREQ=DAILY;BYHOUR=24", "", true},
+		{"FREQ=MONTHLY;BYMONTHDAY=0", "", true},
+		{"FREQ=MONTHLY;BYMONTHDAY=13;BYDAY=FR", "", true},
+		{"FREQ=MONTHLY;BYDAY=6MO", "", true},
+		{"FREQ=WEEKLY;BYDAY=2MO", "", true},
+		{"FREQ=YEARLY;BYDAY=-1MO", "", true},
+		{"FREQ=MONTHLY;BYDAY=1MO,FR", "", true},
+		{"FREQ=MONTHLY;BYDAY=XX", "", true},
+		{"FREQ=DAILY;BYHOUR=9;BYHOUR=10", "", true},
+	}
+
+	for i, p := range params {
+		ts, err := NewFromRRULE(p.rrule, false)
+		if err != nil {
+			if !p.isErr {
+				t.Errorf(`[%d] "%s": unexpected error: %s`, i, p.rrule, err)
+			}
+			continue
+		}
+
+		if p.isErr {
+			t.Errorf(`[%d] "%s": error expected`, i, p.rrule)
+			continue
+		}
+
+		if s := ts.String(); s != p.pattern {
+			t.Errorf(`[%d] "%s": got "%s", expected "%s"`, i, p.rrule, s, p.pattern)
+		}
+	}
+
+	if _, err := NewFromRRULE("FREQ=WEEKLY;BYDAY=MO,MO", false); err == nil || !strings.Contains(err.Error(), `duplicate weekday "MO"`) {
+		t.Errorf(`"BYDAY=MO,MO": got %v, the duplicate weekday error expected`, err)
+	}
+
+	// round trip
+	for _, pattern := range []string{"W^2 w1 h9 m0", "M12 D25 h0 m0", "D$1 h23 m59 s59", "w0,6 h10"} {
+		ts, err := New(pattern, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rule, ok := ts.RRULE()
+		if !ok {
+			t.Fatalf(`"%s": RRULE failed`, pattern)
+		}
+
+		ts, err = NewFromRRULE(rule, false)
+		if err != nil {
+			t.Fatalf(`"%s": %s`, rule, err)
+		}
+
+		if s := ts.String(); s != pattern {
+			t.Errorf(`"%s": got "%s", expected "%s"`, rule, s, pattern)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestNormalizationDrift(t *testing.T) {
+	const day = 24 * time.Hour
+
+	src := tConv("2021-02-10T14:55:22Z")
+
+	params := []struct {
+		pattern  string
+		options  *Options
+		expected time.Duration
+	}{
+		{"", nil, 0},
+		{"D15 h9", nil, 0},
+		{"D+30 h+30", nil, 0},
+		{"D31", nil, 3 * day},
+		{"D$31", nil, -3 * day},
+		{"h24", nil, time.Hour},
+		{"M2 D30 h25", nil, 2*day + 2*time.Hour},
+		{"M13 D15", nil, 31 * day},
+		{"l1500", nil, 501 * time.Millisecond},
+		{"h25", &Options{WrapTimeOfDay: true}, 0},
+		{"D31", &Options{ClampDay: true}, 0},
+		{"W^5 w1", nil, 7 * day},
+		{"W^6 w1", nil, 14 * day},
+		{"W$6 w1", nil, -14 * day},
+		{"W^4 w1", nil, 0},
+		{"W^6 w1", &Options{ClampWeek: true}, 0},
+		{"W60", nil, 0},
+		{"M+1 D$1", nil, 0},
+	}
+
+	for i, p := range params {
+		ts, err := NewWithOptions(p.pattern, false, p.options)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		if d := ts.NormalizationDrift(src); d != p.expected {
+			t.Errorf(`[%d] "%s": got %s, expected %s`, i, p.pattern, d, p.expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestStrictWeekdayError(t *testing.T) {
+	for _, p := range []struct {
+		pattern string
+		name    string
+	}{
+		{pattern: "D15 w2", name: `"w"`},
+		{pattern: "D15 d2", name: `"d"`},
+	} {
+		_, err := NewWithOptions(p.pattern, false, &Options{StrictWeekday: true})
+		if err == nil || !strings.HasPrefix(err.Error(), p.name) {
+			t.Errorf(`"%s": got %v, the error about %s expected`, p.pattern, err, p.name)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestInactiveParts(t *testing.T) {
+	params := []struct {
+		pattern string
+		t       time.Time
+		off     func(ts *TimeShift)
+	}{
+		{pattern: "w0,6 h9", t: tConv("2021-03-10T14:55:22Z"), off: func(ts *TimeShift) { ts.weekday.active = false }},
+		{pattern: "D!13 h9", t: tConv("2021-03-13T14:55:22Z"), off: func(ts *TimeShift) { ts.day.active = false }},
+	}
+
+	for i, p := range params {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		c := *ts
+		p.off(&c) // the flags of the part are kept
+
+		if r, expected := c.Exec(p.t), ts.only(false).Exec(p.t); !r.Equal(expected) {
+			t.Errorf(`[%d] "%s": got "%s", expected "%s"`, i, p.pattern, r, expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestExecFormat(t *testing.T) {
+	src := tConv("2021-03-10T14:55:22.123456789+03:00")
+
+	params := []struct {
+		pattern  string
+		layout   string
+		expected string
+	}{
+		{pattern: "", layout: "", expected: "2021-03-10T14:55:22.123456789+03:00"},
+		{pattern: "D+1 h9", layout: "", expected: "2021-03-11T09:55:22.123456789+03:00"},
+		{pattern: "l0 u0 n0", layout: "", expected: "2021-03-10T14:55:22+03:00"},
+		{pattern: "D+1 h9", layout: time.DateTime, expected: "2021-03-11 09:55:22"},
+		{pattern: "D+1 h9", layout: jsonTZLayout, expected: "2021-03-11T09:55:22.123+03:00"},
+	}
+
+	for i, p := range params {
+		ts, err := New(p.pattern, false)
+		if err != nil {
+			t.Fatalf(`[%d] "%s": %s`, i, p.pattern, err)
+		}
+
+		if s := ts.ExecFormat(src, p.layout); s != p.expected {
+			t.Errorf(`[%d] "%s" with "%s": got "%s", expected "%s"`, i, p.pattern, p.layout, s, p.expected)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
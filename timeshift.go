@@ -1,8 +1,10 @@
 package timeshift
 
 import (
+	"bytes"
 	"fmt"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -12,40 +14,60 @@ import (
 //----------------------------------------------------------------------------------------------------------------------------//
 
 type (
-	// TimeShift --
+	// TimeShift -- immutable after New, so it can be shared between goroutines (the cached objects are shared).
+	// Methods must never change the receiver, anything that needs changes must work on a copy
 	TimeShift struct {
 		empty   bool
+		options *Options
 		year    partDef
 		month   partDef
 		day     partDef
 		week    partDef
+		weeks   partDef
 		weekday partDef
+		isoWd   partDef
 		hour    partDef
 		minute  partDef
 		second  partDef
+		daySec  partDef
 		milli   partDef
 		micro   partDef
 		nano    partDef
+
+		then *TimeShift // the follow-on stage added by AndThen
 	}
 
 	partDef struct {
 		active    bool
 		val       int
 		absolute  bool
-		fromBegin bool // for week only
-		fromEnd   bool // for day and week only
+		fromBegin bool  // for week only
+		fromEnd   bool  // for day and week only
+		nearest   bool  // for day only
+		set       uint8 // for weekday only: the bitmask of the list members (1 << weekday), 0 - a single value
 	}
 )
 
 var (
-	partExpression  = `(?:\s*)([YMDWwhmslun])([\^\$]?)([+-]?)(\d+)(?:\s*)`
-	checkExpression = fmt.Sprintf(`^(%s)+$`, partExpression)
+	partExpression = `(?:\s*)([` + tokenOrder + `])([\^\$!]?)([+-]?)(\d+(?:,\d+)*)(?:\s*)`
 
-	checkRE = regexp.MustCompile(checkExpression)
 	splitRE = regexp.MustCompile(partExpression)
 
 	cacheMutex sync.RWMutex
-	cache      = map[string]*TimeShift{}
+	cache      = map[cacheKey]*TimeShift{}
+)
+
+type cacheKey struct {
+	pattern string
+	options *Options
+}
+
+const (
+	// the canonical order of parts
+	tokenOrder = "YMDWKwdhmsSlun"
+
+	// the limit of the absolute and relative year values
+	maxYear = 9999
 )
 
 const (
@@ -60,16 +82,119 @@ const (
 
 // New --
 func New(pattern string, cached bool) (ts *TimeShift, err error) {
-	pattern = strings.TrimSpace(pattern)
+	return NewWithOptions(pattern, cached, nil)
+}
+
+// AndThen -- the copy of the shift with the follow-on stage: Exec applies the pattern (with the same options) to the result.
+// The stages are applied by Exec and the methods based on it, the methods that apply a subset of the parts (ExecDateOnly, ExecTimeOnly,
+// ExecDate, Matches) and the descriptive ones (String, PartCount, Compare and so on) use the first stage only
+func (ts *TimeShift) AndThen(pattern string) (c *TimeShift, err error) {
+	next, err := NewWithOptions(pattern, false, ts.opts())
+	if err != nil {
+		return
+	}
+
+	if next.isEmpty() {
+		c = ts
+		return
+	}
+
+	if ts.isEmpty() {
+		c = next
+		return
+	}
+
+	c = ts.withStage(next)
+	return
+}
+
+// withStage -- the copy with the stage added to the end of the chain
+func (ts *TimeShift) withStage(next *TimeShift) *TimeShift {
+	c := *ts
+
+	if c.then == nil {
+		c.then = next
+	} else {
+		c.then = c.then.withStage(next)
+	}
+
+	return &c
+}
+
+// NewRequired -- New that returns an error for the empty pattern (or the pattern that is expanded to the empty one).
+// There are no comments in the patterns, so only the whitespace patterns are empty
+func NewRequired(pattern string, cached bool) (ts *TimeShift, err error) {
+	ts, err = New(pattern, cached)
+	if err == nil && ts.isEmpty() {
+		ts = nil
+		err = fmt.Errorf(`empty pattern "%s"`, pattern)
+	}
+
+	return
+}
+
+// NewUncached -- New(pattern, false), it never reads or writes the cache
+func NewUncached(pattern string) (ts *TimeShift, err error) {
+	return NewWithOptions(pattern, false, nil)
+}
+
+// WarmCache -- New(pattern, true) for all patterns. All of them are processed, the first error is returned
+func WarmCache(patterns []string) (err error) {
+	for i, pattern := range patterns {
+		_, e := New(pattern, true)
+		if e != nil && err == nil {
+			err = fmt.Errorf(`pattern %d "%s": %s`, i, pattern, e)
+		}
+	}
+
+	return
+}
+
+// WarmAndVerify -- WarmCache that also runs ExecChecked for ref in UTC, so the absolute values that would be normalized
+// for ref (D31 in the short month and so on) are reported. All patterns are processed, the first error is returned
+func WarmAndVerify(patterns []string, ref time.Time) (err error) {
+	ref = ref.UTC()
+
+	for i, pattern := range patterns {
+		ts, e := New(pattern, true)
+		if e == nil {
+			_, e = ts.ExecChecked(ref)
+		}
+
+		if e != nil && err == nil {
+			err = fmt.Errorf(`pattern %d "%s": %s`, i, pattern, e)
+		}
+	}
+
+	return
+}
+
+// NewWithOptions -- options may be nil. The options object is a part of the cache key, so don't change it after use.
+// @name references are replaced by the patterns registered by RegisterAlias before parsing
+func NewWithOptions(pattern string, cached bool, options *Options) (ts *TimeShift, err error) {
+	pattern, err = expandAliases(strings.TrimSpace(pattern))
+	if err != nil {
+		return
+	}
+
+	pattern, err = expandShorthands(pattern)
+	if err != nil {
+		return
+	}
 
 	if pattern == "" {
-		ts = &TimeShift{empty: true}
+		ts = &TimeShift{empty: true, options: options}
 		return
 	}
 
+	key := cacheKey{pattern: pattern, options: options}
+	if cached && options != nil && options.NormalizeCacheKey {
+		key.pattern = normalizeSpaces(pattern)
+	}
+
 	if cached {
 		cacheMutex.RLock()
-		ts = cache[pattern]
+		ts = cache[key]
 		cacheMutex.RUnlock()
 
 		if ts != nil {
@@ -77,32 +202,56 @@ func New(pattern string, cached bool) (ts *TimeShift, err error) {
 		}
 	}
 
-	ts = &TimeShift{empty: false}
+	ts = &TimeShift{empty: false, options: options}
 
 	defer func() {
 		if err != nil {
 			ts = nil
-		} else if cached {
+		} else if cached && ts.Deterministic() {
 			cacheMutex.Lock()
-			cache[pattern] = ts
+			cache[key] = ts
 			cacheMutex.Unlock()
 		}
 	}()
 
-	// Common check
-	parts := checkRE.FindAllStringSubmatch(pattern, -1)
-	if len(parts) == 0 {
+	// Split to parts, they must cover the whole pattern without gaps
+	idx := splitRE.FindAllStringSubmatchIndex(pattern, -1)
+	parts := make([][]string, len(idx))
+	end := 0
+
+	if len(idx) != 0 {
+		n := len(idx[0]) / 2
+		buf := make([]string, len(idx)*n)
+
+		for i, loc := range idx {
+			if loc[0] != end {
+				break
+			}
+			end = loc[1]
+
+			part := buf[i*n : (i+1)*n : (i+1)*n]
+			for j := range part {
+				part[j] = pattern[loc[2*j]:loc[2*j+1]]
+			}
+			parts[i] = part
+		}
+	}
+
+	if len(parts) == 0 || end != len(pattern) {
 		err = fmt.Errorf(`illegal pattern "%s"`, pattern)
 		return
 	}
 
-	// Split to parts
-	parts = splitRE.FindAllStringSubmatch(pattern, -1)
-
 	// Parts sequence pattern
-	partNames := []byte("YMDWwhmslun!")
+	partNames := []byte(tokenOrder + "!")
 	nameIdx := 0
 
+	if ts.opts().AllowAnyOrder {
+		sort.SliceStable(parts, func(i, j int) bool {
+			return bytes.IndexByte(partNames, parts[i][partName][0]) < bytes.IndexByte(partNames, parts[j][partName][0])
+		})
+	}
+
 	for _, part := range parts {
 		name := part[partName]
 
@@ -118,7 +267,44 @@ func New(pattern string, cached bool) (ts *TimeShift, err error) {
 			return
 		}
 
-		v, _ := strconv.ParseInt(part[partVal], 10, 32)
+		val := part[partVal]
+
+		var set uint8
+		if strings.IndexByte(val, ',') >= 0 {
+			if name != "w" {
+				err = fmt.Errorf(`the list of values is allowed for the weekday only, illegal "%s"`, part[partSrc])
+				return
+			}
+
+			list := strings.Split(val, ",")
+			for _, s := range list {
+				n, e := strconv.Atoi(s)
+				if e != nil || n > 6 {
+					err = fmt.Errorf(`illegal weekday in the "%s"`, part[partSrc])
+					return
+				}
+
+				bit := uint8(1) << uint(n)
+				if set&bit != 0 {
+					err = fmt.Errorf(`duplicate weekday in the "%s"`, part[partSrc])
+					return
+				}
+				set |= bit
+			}
+
+			// the smallest member is the value
+			n := 0
+			for set&(1<<uint(n)) == 0 {
+				n++
+			}
+			val = strconv.Itoa(n)
+		}
+
+		v, e := strconv.ParseInt(val, 10, 32)
+		if e != nil {
+			err = fmt.Errorf(`illegal value in the "%s": %s`, part[partSrc], e)
+			return
+		}
 
 		pDf := partDef{
 			active:    true,
@@ -126,6 +312,7 @@ func New(pattern string, cached bool) (ts *TimeShift, err error) {
 			absolute:  true,
 			fromBegin: false,
 			fromEnd:   false,
+			set:       set,
 		}
 
 		switch part[partSign] {
@@ -136,28 +323,29 @@ func New(pattern string, cached bool) (ts *TimeShift, err error) {
 			pDf.val = -pDf.val
 		}
 
+		info := tokenInfo(name[0])
+		monthSnap := name == "M" && !pDf.absolute && ts.opts().AllowMonthSnap
+
 		for _, c := range part[partOptions] {
-			switch c {
-			case '^':
-				switch name {
-				case "W":
-					pDf.fromBegin = true
-				default:
-					err = fmt.Errorf(`illegal option "%c" in the "%s"`, c, part[partSrc])
-					return
-				}
-			case '$':
-				switch name {
-				case "D", "W":
-					pDf.fromEnd = true
-				default:
-					err = fmt.Errorf(`illegal option "%c" in the "%s"`, c, part[partSrc])
-					return
-				}
+			switch {
+			case c == '^' && (info.Begin || monthSnap):
+				pDf.fromBegin = true
+			case c == '$' && info.End:
+				pDf.fromEnd = true
+			case c == '!' && info.Nearest:
+				pDf.nearest = true
+			default:
+				err = fmt.Errorf(`illegal option "%c" in the "%s"`, c, part[partSrc])
+				return
 			}
 		}
 
-		if (pDf.fromBegin || pDf.fromEnd) && !pDf.absolute {
+		if pDf.nearest && !pDf.absolute {
+			err = fmt.Errorf(`"!" can not be used with relative ("+" or "-") values in the "%s"`, part[partSrc])
+			return
+		}
+
+		if (pDf.fromBegin || pDf.fromEnd) && !pDf.absolute && !monthSnap {
 			err = fmt.Errorf(`"^" and "$" can not be used with relative ("+" or "-") values in the "%s"`, part[partSrc])
 			return
 		}
@@ -169,6 +357,10 @@ func New(pattern string, cached bool) (ts *TimeShift, err error) {
 
 		switch name {
 		case "Y":
+			if pDf.val < -maxYear || pDf.val > maxYear {
+				err = fmt.Errorf(`year is out of the [-%d, %d] range in the "%s"`, maxYear, maxYear, part[partSrc])
+				return
+			}
 			ts.year = pDf
 
 		case "M":
@@ -180,7 +372,7 @@ func New(pattern string, cached bool) (ts *TimeShift, err error) {
 
 		case "D":
 			if pDf.active {
-				if pDf.absolute && pDf.val == 0 {
+				if pDf.absolute && pDf.val == 0 && !(pDf.fromEnd && ts.opts().FromEndZeroBased) {
 					err = fmt.Errorf(`illegal day in the "%s"`, part[partSrc])
 					return
 				}
@@ -203,7 +395,21 @@ func New(pattern string, cached bool) (ts *TimeShift, err error) {
 			}
 			ts.week = pDf
 
+		case "K":
+			// whole weeks, relative only
+			if pDf.absolute {
+				err = fmt.Errorf(`"K" must be relative ("+" or "-") in the "%s"`, part[partSrc])
+				return
+			}
+			ts.weeks = pDf
+
 		case "w":
+			if pDf.val < 0 && pDf.set == 0 && ts.opts().WeekdayFromEnd {
+				// -1 - Saturday, -7 - Sunday
+				pDf.val += 7
+				pDf.absolute = true
+			}
+
 			// 0 - Sunday
 			if pDf.val < 0 || pDf.val > 6 {
 				err = fmt.Errorf(`illegal weekday in the "%s"`, part[partSrc])
@@ -211,6 +417,14 @@ func New(pattern string, cached bool) (ts *TimeShift, err error) {
 			}
 			ts.weekday = pDf
 
+		case "d":
+			// 1 - Monday, 7 - Sunday
+			if !pDf.absolute || pDf.val < 1 || pDf.val > 7 {
+				err = fmt.Errorf(`illegal ISO weekday in the "%s"`, part[partSrc])
+				return
+			}
+			ts.isoWd = pDf
+
 		case "h":
 			ts.hour = pDf
 
@@ -219,6 +433,8 @@ func New(pattern string, cached bool) (ts *TimeShift, err error) {
 
 		case "s":
 			ts.second = pDf
+		case "S":
+			ts.daySec = pDf
 		case "l":
 			ts.milli = pDf
 		case "u":
@@ -228,25 +444,385 @@ func New(pattern string, cached bool) (ts *TimeShift, err error) {
 		}
 	}
 
+	if ts.isoWd.active && ts.weekday.active {
+		err = fmt.Errorf(`"d" can not be used with "w" in "%s"`, pattern)
+		return
+	}
+
+	if ts.isoWd.active && ts.week.active && ts.week.absolute {
+		err = fmt.Errorf(`"d" can be used with the relative "W" only in "%s"`, pattern)
+		return
+	}
+
+	if err = ts.checkConflicts(pattern); err != nil {
+		return
+	}
+
+	if ts.opts().StrictWeekday && (ts.weekday.active || ts.isoWd.active) && ts.day.active && ts.day.absolute && !ts.week.active {
+		name := "w"
+		if ts.isoWd.active {
+			name = "d"
+		}
+		err = fmt.Errorf(`"%s" with the absolute "D" without "W" moves the date off the day in "%s"`, name, pattern)
+		return
+	}
+
+	if ts.weekday.set != 0 && ts.week.active {
+		err = fmt.Errorf(`the list of weekdays can not be used with "W" in "%s"`, pattern)
+		return
+	}
+
+	if ts.weeks.active && ts.week.active {
+		err = fmt.Errorf(`"K" can not be used with "W" in "%s"`, pattern)
+		return
+	}
+
+	if ts.daySec.active && (ts.hour.active || ts.minute.active || ts.second.active) {
+		err = fmt.Errorf(`"S" can not be used with "h", "m" or "s" in "%s"`, pattern)
+		return
+	}
+
+	return
+}
+
+// checkConflicts -- StrictConflicts check of the parts that are overridden by other ones
+func (ts *TimeShift) checkConflicts(pattern string) (err error) {
+	opts := ts.opts()
+	if !opts.StrictConflicts || !ts.week.active || !ts.weekday.active {
+		return
+	}
+
+	anchored := ts.week.fromBegin || ts.week.fromEnd
+	fromYear := ts.week.absolute && !anchored && (!opts.WeekFromInput || opts.FirstWeekRule != FirstWeekFromJan1)
+
+	if ts.day.active && (anchored || fromYear) {
+		err = fmt.Errorf(`"D" is ignored, the day is defined by "W" and "w" in "%s"`, pattern)
+		return
+	}
+
+	if ts.month.active && fromYear {
+		err = fmt.Errorf(`"M" is ignored, the absolute "W" is counted from the begin of the year in "%s"`, pattern)
+		return
+	}
+
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// isEmpty -- nil is the same as the empty shift
+func (ts *TimeShift) isEmpty() bool {
+	return ts == nil || ts.empty
+}
+
+func (ts *TimeShift) opts() *Options {
+	if ts == nil || ts.options == nil {
+		return &defaultOptions
+	}
+	return ts.options
+}
+
+// parts in the canonical order
+func (ts *TimeShift) parts() []*partDef {
+	if ts == nil {
+		ts = &TimeShift{empty: true}
+	}
+
+	return []*partDef{
+		&ts.year, &ts.month, &ts.day, &ts.week, &ts.weeks, &ts.weekday, &ts.isoWd,
+		&ts.hour, &ts.minute, &ts.second, &ts.daySec,
+		&ts.milli, &ts.micro, &ts.nano,
+	}
+}
+
+// only returns a copy that has the date (date == true) or the time parts only
+func (ts *TimeShift) only(date bool) *TimeShift {
+	c := *ts
+
+	off := []*partDef{&c.year, &c.month, &c.day, &c.week, &c.weeks, &c.weekday, &c.isoWd}
+	if date {
+		off = []*partDef{&c.hour, &c.minute, &c.second, &c.daySec, &c.milli, &c.micro, &c.nano}
+	}
+
+	for _, df := range off {
+		*df = partDef{} // the flags of the inactive part must not be used by exec
+	}
+
+	c.then = nil
+	return &c
+}
+
+// relativeOnly returns a copy that has the relative (relative == true) or the absolute and anchored parts only. Weekday is always absolute
+func (ts *TimeShift) relativeOnly(relative bool) *TimeShift {
+	c := *ts
+
+	for _, df := range c.parts() {
+		if df == &c.weekday {
+			if relative {
+				*df = partDef{}
+			}
+			continue
+		}
+
+		if df.absolute == relative {
+			*df = partDef{} // the flags of the inactive part must not be used by exec
+		}
+	}
+
+	c.then = nil
+	return &c
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ExecChecked -- Exec with checking of the source time against the options and of the absolute values that are out of the range
+// of their unit and would be normalized into the larger unit (M13, h24, m60, D31 in February and so on) unless the options
+// wrap or clamp them. The result is the source time if there is an error
+func (ts *TimeShift) ExecChecked(t time.Time) (result time.Time, err error) {
+	opts := ts.opts()
+	result = t
+
+	if opts.RequireLocation != nil && t.Location().String() != opts.RequireLocation.String() {
+		err = fmt.Errorf(`location "%s" is not allowed, expected "%s"`, t.Location(), opts.RequireLocation)
+		return
+	}
+
+	if err = ts.checkRanges(t); err != nil {
+		return
+	}
+
+	result = ts.Exec(t)
+	return
+}
+
+// ExecOrInput -- ExecChecked result or the source time if there is an error
+func (ts *TimeShift) ExecOrInput(t time.Time) time.Time {
+	result, _ := ts.ExecChecked(t)
+	return result
+}
+
+// checkRanges -- the absolute values that are normalized into the larger unit
+func (ts *TimeShift) checkRanges(t time.Time) (err error) {
+	if ts.isEmpty() {
+		return
+	}
+
+	opts := ts.opts()
+	wrapMS := opts.WrapTimeOfDay || opts.WrapMinuteSecond
+
+	list := []struct {
+		df   *partDef
+		name byte
+		max  int
+		skip bool
+	}{
+		{df: &ts.month, name: 'M', max: 12},
+		{df: &ts.hour, name: 'h', max: 23, skip: opts.WrapTimeOfDay},
+		{df: &ts.minute, name: 'm', max: 59, skip: wrapMS},
+		{df: &ts.second, name: 's', max: 59, skip: wrapMS},
+		{df: &ts.daySec, name: 'S', max: 24*3600 - 1, skip: opts.WrapTimeOfDay},
+		{df: &ts.milli, name: 'l', max: 999},
+		{df: &ts.micro, name: 'u', max: 999},
+		{df: &ts.nano, name: 'n', max: 999},
+	}
+
+	for _, p := range list {
+		if p.df.active && p.df.absolute && !p.skip && p.df.val > p.max {
+			err = fmt.Errorf(`"%c%d" is out of the [0, %d] range`, p.name, p.df.val, p.max)
+			return
+		}
+	}
+
+	if ts.day.active && ts.day.absolute && !ts.day.nearest && !opts.ClampDay {
+		// the month of the result before the day is applied
+		ym := TimeShift{options: ts.options, year: ts.year, month: ts.month}
+		ym.month.fromBegin = false
+		target := ym.exec(time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC))
+
+		max := DaysInMonth(target.Year(), target.Month())
+		option := ""
+		if ts.day.fromEnd {
+			option = "$"
+			if opts.FromEndZeroBased {
+				max--
+			}
+		}
+
+		if ts.day.val > max {
+			err = fmt.Errorf(`"D%s%d" is out of the [1, %d] range for %s %d`, option, ts.day.val, max, target.Month(), target.Year())
+			return
+		}
+	}
+
 	return
 }
 
+// NormalizationDrift -- Exec(t) minus the result with the out of range absolute values (see ExecChecked) clamped to their ranges:
+// "D31" in February gives 3 days (Mar 3 instead of Feb 28) in the non-leap year, "h24" gives 1 hour. W^ and W$ that go out
+// of the month are clamped as with ClampWeek: "W^5 w1" in February 2021 gives 7 days. The absolute W out of the year
+// and the relative parts are not counted. 0 - no normalization
+func (ts *TimeShift) NormalizationDrift(t time.Time) time.Duration {
+	if ts.isEmpty() {
+		return 0
+	}
+
+	opts := *ts.opts()
+
+	outOfRange := ts.checkRanges(t) != nil
+	weekSpill := ts.week.active && (ts.week.fromBegin || ts.week.fromEnd) && !opts.ClampWeek
+
+	if !outOfRange && !weekSpill {
+		return 0
+	}
+
+	c := *ts
+
+	wrapMS := opts.WrapTimeOfDay || opts.WrapMinuteSecond
+
+	for _, p := range []struct {
+		df   *partDef
+		max  int
+		skip bool
+	}{
+		{df: &c.month, max: 12},
+		{df: &c.hour, max: 23, skip: opts.WrapTimeOfDay},
+		{df: &c.minute, max: 59, skip: wrapMS},
+		{df: &c.second, max: 59, skip: wrapMS},
+		{df: &c.daySec, max: 24*3600 - 1, skip: opts.WrapTimeOfDay},
+		{df: &c.milli, max: 999},
+		{df: &c.micro, max: 999},
+		{df: &c.nano, max: 999},
+	} {
+		if p.df.active && p.df.absolute && !p.skip && p.df.val > p.max {
+			p.df.val = p.max
+		}
+	}
+
+	if outOfRange {
+		opts.ClampDay = true // the day in the target month
+	}
+	opts.ClampWeek = true // the week in the month
+	c.options = &opts
+
+	return ts.Exec(t).Sub(c.Exec(t))
+}
+
 //----------------------------------------------------------------------------------------------------------------------------//
 
-// Exec --
+// Exec -- nil receiver is allowed and works as the empty shift (it's true for all methods)
 func (ts *TimeShift) Exec(t time.Time) (result time.Time) {
-	if ts.empty {
+	if ts.isEmpty() {
 		result = t
 		return
 	}
 
+	if ts.opts().RelativeFirst {
+		result = ts.relativeOnly(false).exec(ts.relativeOnly(true).exec(t))
+	} else {
+		result = ts.exec(t)
+	}
+
+	if dir := ts.opts().AbsoluteDirection; dir != AbsoluteSame {
+		result = ts.resolveDirection(t, result, dir)
+	}
+
+	if ts.then != nil {
+		result = ts.then.Exec(result)
+	}
+
+	return
+}
+
+// resolveDirection -- moves the result by one unit above the coarsest absolute time part to the direction from the source
+// with the relative parts applied. Nothing is done if there are absolute or anchored date parts or there are no absolute time parts
+func (ts *TimeShift) resolveDirection(t time.Time, result time.Time, dir AbsoluteDirection) time.Time {
+	parts := ts.parts()
+
+	unit := -1 // the index of the tokenOrder
+	for i, df := range parts {
+		if !df.active || !df.absolute {
+			continue
+		}
+
+		if i < strings.IndexByte(tokenOrder, 'h') {
+			return result // the date is fixed
+		}
+
+		if unit < 0 {
+			unit = i
+		}
+	}
+
+	if unit < 0 {
+		return result
+	}
+
+	ref := t
+	if rel := ts.relativeOnly(true); !rel.isEmpty() {
+		ref = rel.exec(t)
+	}
+
+	step := func(n int) time.Time {
+		year, month, day := result.Date()
+		hour, minute, second := result.Clock()
+		nsec := result.Nanosecond()
+
+		switch tokenOrder[unit] {
+		case 'h', 'S':
+			day += n
+		case 'm':
+			hour += n
+		case 's':
+			minute += n
+		case 'l':
+			second += n
+		case 'u':
+			nsec += n * int(time.Millisecond)
+		default:
+			nsec += n * int(time.Microsecond)
+		}
+
+		return time.Date(year, month, day, hour, minute, second, nsec, result.Location())
+	}
+
+	switch {
+	case result.Before(ref) && dir == AbsoluteForward:
+		return step(1)
+
+	case result.After(ref) && dir == AbsoluteBackward:
+		return step(-1)
+
+	case dir == AbsoluteNearest:
+		n := 1
+		if result.After(ref) {
+			n = -1
+		}
+
+		if other := step(n); absDuration(other.Sub(ref)) < absDuration(result.Sub(ref)) {
+			return other
+		}
+	}
+
+	return result
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func (ts *TimeShift) exec(t time.Time) (result time.Time) {
+	opts := ts.opts()
+
 	proc := func(df *partDef, v *int) {
 		if !df.active {
 			return
 		}
 
 		if df.fromEnd {
-			return // save the old value temporarily
+			return // processed separately
 		}
 
 		if df.absolute {
@@ -257,41 +833,133 @@ func (ts *TimeShift) Exec(t time.Time) (result time.Time) {
 		*v += df.val
 	}
 
+	wrap := func(df *partDef, v *int, n int) {
+		if df.active && df.absolute {
+			*v %= n
+		}
+	}
+
+	// M^+n - the begin of the month, the finer parts are applied to it
+	snap := ts.month.active && ts.month.fromBegin
+
 	hour, minute, second := t.Clock()
-	s := t.UnixNano()
-	milli := int((s / int64(time.Millisecond)) % 1000)
-	micro := int((s / int64(time.Microsecond)) % 1000)
-	nano := int((s / int64(time.Nanosecond)) % 1000)
+	if snap {
+		hour, minute, second = 0, 0, 0
+	}
+
+	s := t.Nanosecond() // not UnixNano, it overflows out of the 1678-2262 years
+	if snap {
+		s = 0
+	}
+	milli := (s / int(time.Millisecond)) % 1000
+	micro := (s / int(time.Microsecond)) % 1000
+	nano := (s / int(time.Nanosecond)) % 1000
+
+	proc(&ts.milli, &milli)
+	proc(&ts.micro, &micro)
+	proc(&ts.nano, &nano)
+
+	nsec := milli*int(time.Millisecond) + micro*int(time.Microsecond) + nano*int(time.Nanosecond)
 
 	year, m, day := t.Date()
 	month := int(m)
 
+	monthEnd := opts.PreserveMonthEnd && ts.month.active && !ts.month.absolute && !snap && !ts.day.active && day == DaysInMonth(year, m)
+
 	proc(&ts.hour, &hour)
 	proc(&ts.minute, &minute)
 	proc(&ts.second, &second)
 
-	proc(&ts.milli, &milli)
-	proc(&ts.micro, &micro)
-	proc(&ts.nano, &nano)
+	if opts.WrapTimeOfDay {
+		wrap(&ts.hour, &hour, 24)
+	}
+
+	if opts.WrapTimeOfDay || opts.WrapMinuteSecond {
+		wrap(&ts.minute, &minute, 60)
+		wrap(&ts.second, &second, 60)
+	}
+
+	if ts.daySec.active {
+		// seconds of the day, the values out of the day are normalized by time.Date
+		s := hour*3600 + minute*60 + second
+		proc(&ts.daySec, &s)
+		if opts.WrapTimeOfDay {
+			wrap(&ts.daySec, &s, 24*3600)
+		}
+		hour, minute, second = 0, 0, s
+	}
 
 	proc(&ts.year, &year)
 	proc(&ts.month, &month)
+	if snap {
+		day = 1
+	}
 	proc(&ts.day, &day)
 
+	if monthEnd {
+		day = DaysInMonth(year, time.Month(month))
+	}
+
+	if ts.day.active {
+		dim := DaysInMonth(year, time.Month(month))
+
+		if ts.day.fromEnd && opts.ClampDay {
+			// from the end of the target month
+			day = dim - ts.day.val + 1
+			if opts.FromEndZeroBased {
+				day--
+			}
+			if day < 1 {
+				day = 1
+			}
+		} else if ts.day.absolute && (opts.ClampDay || ts.day.nearest) && day > dim {
+			// D! does not cross the month boundary too
+			day = dim
+		}
+	}
+
 	result = time.Date(
 		year, time.Month(month), day,
 		hour, minute, second,
-		milli*int(time.Millisecond)+micro*int(time.Microsecond)+nano*int(time.Nanosecond),
+		nsec,
 		t.Location(),
 	)
 
-	if ts.day.fromEnd {
-		result = result.AddDate(0, 1, -day-ts.day.val+1)
+	if ts.day.active && ts.day.fromEnd && !opts.ClampDay {
+		// from the end of the month of the normalized result, so "M+1 D$1" on Jan 31 gives Mar 3
+		shift := -day - ts.day.val + 1
+		if opts.FromEndZeroBased {
+			shift--
+		}
+		result = result.AddDate(0, 1, shift)
+	}
+
+	if ts.day.active && ts.day.nearest {
+		// the nearest weekday (Mon-Fri) without crossing the month boundary
+		switch result.Weekday() {
+		case time.Saturday:
+			if result.Day() == 1 {
+				result = result.AddDate(0, 0, 2)
+			} else {
+				result = result.AddDate(0, 0, -1)
+			}
+		case time.Sunday:
+			if result.AddDate(0, 0, 1).Month() != result.Month() {
+				result = result.AddDate(0, 0, -2) // the last day of the month
+			} else {
+				result = result.AddDate(0, 0, 1)
+			}
+		}
+	}
+
+	if opts.BusinessDayConvention != BusinessDayNone && ts.day.active && ts.day.absolute && !ts.day.nearest {
+		result = adjustBusinessDay(result, opts.BusinessDayConvention)
 	}
 
 	if ts.week.active {
 		df := ts.week
 
+		// without w the weekday of the source (after the date parts processing) is kept
 		var wd int
 		if ts.weekday.active {
 			wd = ts.weekday.val
@@ -302,20 +970,32 @@ func (ts *TimeShift) Exec(t time.Time) (result time.Time) {
 		if df.fromBegin {
 			// from the begin of the month
 			result = result.AddDate(0, 0, -result.Day()+1) // begin of the month
+			month := result.Month()
 
 			shift := wd - int(result.Weekday())
 			if shift < 0 {
 				shift += 7
 			}
+			if opts.WeekOfMonthRule == WeekOfMonthFull {
+				// from the first Sunday of the month
+				shift = (7-int(result.Weekday()))%7 + wd
+			}
 			shift += (df.val - 1) * 7
 
 			result = result.AddDate(0, 0, shift)
+
+			if opts.ClampWeek {
+				for result.Month() != month {
+					result = result.AddDate(0, 0, -7) // back to the last week of the month
+				}
+			}
 			return // weekday already taken
 		}
 
 		if df.fromEnd {
 			// from the end of the month
 			result = result.AddDate(0, 1, -result.Day()) // end of the month
+			month := result.Month()
 
 			shift := wd - int(result.Weekday())
 			if shift > 0 {
@@ -324,6 +1004,34 @@ func (ts *TimeShift) Exec(t time.Time) (result time.Time) {
 			shift -= (df.val - 1) * 7
 
 			result = result.AddDate(0, 0, shift)
+
+			if opts.ClampWeek {
+				for result.Month() != month {
+					result = result.AddDate(0, 0, 7) // forward to the first week of the month
+				}
+			}
+			return // weekday already taken
+		}
+
+		if df.absolute && opts.FirstWeekRule != FirstWeekFromJan1 {
+			// the weekday of the n-th week, WeekFromInput gives the same
+			result = week1Start(result, opts.FirstWeekRule).AddDate(0, 0, (df.val-1)*7+wd)
+			return // weekday already taken
+		}
+
+		if df.absolute && opts.WeekFromInput {
+			// move the source by whole Sunday based weeks of its year (week 1 contains Jan 1) and take the weekday within that week
+			year := result.Year()
+			jan1 := result.AddDate(0, 0, -result.YearDay()+1)
+			n := (result.YearDay()-1+int(jan1.Weekday()))/7 + 1
+			result = result.AddDate(0, 0, (df.val-n)*7+wd-int(result.Weekday()))
+
+			// the weekday out of the year in the partial first (last) week is taken from the next (previous) week
+			if result.Year() < year && df.val == 1 {
+				result = result.AddDate(0, 0, 7)
+			} else if result.Year() > year && result.AddDate(0, 0, -int(result.Weekday())).Year() == year {
+				result = result.AddDate(0, 0, -7)
+			}
 			return // weekday already taken
 		}
 
@@ -345,12 +1053,34 @@ func (ts *TimeShift) Exec(t time.Time) (result time.Time) {
 		result = result.AddDate(0, 0, df.val*7)
 	}
 
+	if ts.weeks.active {
+		// K can not be used with W, so the same as the relative W
+		result = result.AddDate(0, 0, ts.weeks.val*7)
+	}
+
+	if ts.weekday.active && ts.weekday.set != 0 {
+		// the nearest member of the list on or after the date
+		shift := 0
+		for ts.weekday.set&(1<<uint((int(result.Weekday())+shift)%7)) == 0 {
+			shift++
+		}
+		result = result.AddDate(0, 0, shift)
+		return
+	}
+
 	if ts.weekday.active {
 		shift := ts.weekday.val - int(result.Weekday())
 		result = result.AddDate(0, 0, shift)
 		return
 	}
 
+	if ts.isoWd.active {
+		// from the Monday of the ISO week
+		monday := (int(result.Weekday()) + 6) % 7
+		result = result.AddDate(0, 0, ts.isoWd.val-1-monday)
+		return
+	}
+
 	return
 }
 
@@ -0,0 +1,111 @@
+package timeshift
+
+import (
+	"encoding/json"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// TokenInfo -- description of the part
+	TokenInfo struct {
+		Letter  byte
+		Name    string
+		Min     int  // minimal absolute value
+		Max     int  // maximal absolute value, 0 - no limit (the value is normalized)
+		Sign    bool // relative ("+" or "-") values are allowed
+		Begin   bool // "^" is allowed
+		End     bool // "$" is allowed
+		Nearest bool // "!" is allowed
+	}
+)
+
+// in the tokenOrder order
+var tokens = []TokenInfo{
+	{Letter: 'Y', Name: "year", Min: 0, Max: maxYear, Sign: true},
+	{Letter: 'M', Name: "month", Min: 1, Max: 0, Sign: true},
+	{Letter: 'D', Name: "day", Min: 1, Max: 0, Sign: true, End: true, Nearest: true},
+	{Letter: 'W', Name: "week", Min: 1, Max: 0, Sign: true, Begin: true, End: true},
+	{Letter: 'K', Name: "weeks", Min: 0, Max: 0, Sign: true},
+	{Letter: 'w', Name: "weekday", Min: 0, Max: 6},
+	{Letter: 'd', Name: "isoweekday", Min: 1, Max: 7},
+	{Letter: 'h', Name: "hour", Min: 0, Max: 0, Sign: true},
+	{Letter: 'm', Name: "minute", Min: 0, Max: 0, Sign: true},
+	{Letter: 's', Name: "second", Min: 0, Max: 0, Sign: true},
+	{Letter: 'S', Name: "daysecond", Min: 0, Max: 0, Sign: true},
+	{Letter: 'l', Name: "millisecond", Min: 0, Max: 0, Sign: true},
+	{Letter: 'u', Name: "microsecond", Min: 0, Max: 0, Sign: true},
+	{Letter: 'n', Name: "nanosecond", Min: 0, Max: 0, Sign: true},
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// Tokens -- descriptions of the supported parts in the canonical order
+func Tokens() []TokenInfo {
+	list := make([]TokenInfo, len(tokens))
+	copy(list, tokens)
+	return list
+}
+
+func tokenInfo(c byte) *TokenInfo {
+	for i := range tokens {
+		if tokens[i].Letter == c {
+			return &tokens[i]
+		}
+	}
+
+	return &TokenInfo{}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// GrammarJSON -- the JSON description of the parts built from Tokens():
+// {"order": "YMDWKwdhmsSlun", "tokens": [{"letter": "Y", "name": "year", "min": 0, "max": 9999, "sign": true, "options": ""}, ...]}.
+// The options are the allowed "^", "$" and "!" characters, max 0 means no limit
+func GrammarJSON() []byte {
+	type token struct {
+		Letter  string `json:"letter"`
+		Name    string `json:"name"`
+		Min     int    `json:"min"`
+		Max     int    `json:"max"`
+		Sign    bool   `json:"sign"`
+		Options string `json:"options"`
+	}
+
+	doc := struct {
+		Order  string  `json:"order"`
+		Tokens []token `json:"tokens"`
+	}{
+		Order:  tokenOrder,
+		Tokens: make([]token, 0, len(tokens)),
+	}
+
+	for _, t := range tokens {
+		options := ""
+		if t.Begin {
+			options += "^"
+		}
+		if t.End {
+			options += "$"
+		}
+		if t.Nearest {
+			options += "!"
+		}
+
+		doc.Tokens = append(doc.Tokens,
+			token{
+				Letter:  string(t.Letter),
+				Name:    t.Name,
+				Min:     t.Min,
+				Max:     t.Max,
+				Sign:    t.Sign,
+				Options: options,
+			},
+		)
+	}
+
+	j, _ := json.Marshal(doc) // the structure is always marshalable
+	return j
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
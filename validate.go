@@ -0,0 +1,92 @@
+package timeshift
+
+import (
+	"fmt"
+	"strings"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+type (
+	// PatternError -- the problem in the part of the pattern
+	PatternError struct {
+		Offset int    // the byte offset of the part in the pattern (after aliases and shorthands expansion), -1 - the whole pattern
+		Part   string // the source of the part
+		Err    error
+	}
+)
+
+// Error --
+func (e *PatternError) Error() string {
+	if e.Offset < 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf(`offset %d ("%s"): %s`, e.Offset, e.Part, e.Err)
+}
+
+// Unwrap --
+func (e *PatternError) Unwrap() error {
+	return e.Err
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// ValidateAll -- all the problems of the pattern (with the default options) instead of the first one reported by New.
+// The unparsable fragments, the illegal parts and the wrong sequence of parts are reported separately with their offsets,
+// the problems of the parts combination are checked if the parts are valid. nil if the pattern is valid
+func ValidateAll(pattern string) (errs []error) {
+	pattern, err := expandAliases(strings.TrimSpace(pattern))
+	if err == nil {
+		pattern, err = expandShorthands(pattern)
+	}
+	if err != nil {
+		return []error{&PatternError{Offset: -1, Err: err}}
+	}
+
+	add := func(offset int, part string, err error) {
+		errs = append(errs, &PatternError{Offset: offset, Part: strings.TrimSpace(part), Err: err})
+	}
+
+	end := 0
+	prevIdx := -1
+	prevName := byte(0)
+
+	for _, loc := range splitRE.FindAllStringSubmatchIndex(pattern, -1) {
+		if loc[0] != end {
+			add(end, pattern[end:loc[0]], fmt.Errorf(`unparsable fragment`))
+		}
+		end = loc[1]
+
+		src := pattern[loc[0]:loc[1]]
+		offset := loc[0] + len(src) - len(strings.TrimLeft(src, " \t\n\r\v\f"))
+
+		if _, err := New(src, false); err != nil {
+			add(offset, src, err)
+		}
+
+		name := pattern[loc[2*partName]]
+		idx := strings.IndexByte(tokenOrder, name)
+		if idx <= prevIdx {
+			add(offset, src, fmt.Errorf(`"%c" after "%c", the order must be "%s"`, name, prevName, tokenOrder))
+		} else {
+			prevIdx = idx
+			prevName = name
+		}
+	}
+
+	if end != len(pattern) {
+		add(end, pattern[end:], fmt.Errorf(`unparsable fragment`))
+	}
+
+	if len(errs) != 0 {
+		return
+	}
+
+	if _, err := New(pattern, false); err != nil {
+		errs = append(errs, &PatternError{Offset: -1, Err: err})
+	}
+
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
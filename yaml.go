@@ -0,0 +1,66 @@
+//go:build yaml
+
+package timeshift
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+var (
+	// yamlNumberKeys -- the keys which can't be relative, so the YAML number is unambiguous for them
+	yamlNumberKeys = map[string]bool{
+		"isoweekday": true,
+	}
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+// UnmarshalYAML -- the value is the pattern string or the mapping for NewFromMap. It is built with the "yaml" tag only,
+// so the package does not depend on gopkg.in/yaml.v3 by default.
+// The values in the mapping must be strings (except isoweekday), YAML reads the unquoted +1 as the number 1
+func (ts *TimeShift) UnmarshalYAML(value *yaml.Node) (err error) {
+	var v *TimeShift
+
+	switch value.Kind {
+	case yaml.ScalarNode:
+		v, err = New(value.Value, false)
+
+	case yaml.MappingNode:
+		m := make(map[string]string, len(value.Content)/2)
+
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			key := value.Content[i].Value
+			val := value.Content[i+1]
+
+			if val.Kind != yaml.ScalarNode {
+				err = fmt.Errorf(`line %d: a scalar value expected for the "%s"`, val.Line, key)
+				return
+			}
+
+			if val.ShortTag() != "!!str" && !yamlNumberKeys[key] {
+				err = fmt.Errorf(`line %d: the "%s" value %s is not a string, quote it`, val.Line, key, val.Value)
+				return
+			}
+
+			m[key] = val.Value
+		}
+
+		v, err = NewFromMap(m, false)
+
+	default:
+		err = fmt.Errorf(`line %d: a pattern string or a mapping expected`, value.Line)
+	}
+
+	if err != nil {
+		return
+	}
+
+	*ts = *v
+	return
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//
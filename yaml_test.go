@@ -0,0 +1,58 @@
+//go:build yaml
+
+package timeshift
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+//----------------------------------------------------------------------------------------------------------------------------//
+
+func TestUnmarshalYAML(t *testing.T) {
+	src := tConv("2020-06-13T14:55:22Z")
+
+	list := []struct {
+		doc           string
+		errorExpected bool
+		result        time.Time
+	}{
+		{doc: `shift: Y+1 D$1`, result: tConv("2021-06-30T14:55:22Z")},
+		{doc: `shift: ""`, result: src},
+		{doc: `shift: {year: "+1", day: $1, hour: "3"}`, result: tConv("2021-06-30T03:55:22Z")},
+		{doc: `shift: {week: "+1", isoweekday: 1}`, result: tConv("2020-06-15T14:55:22Z")},
+		{doc: `shift: {year: +1}`, errorExpected: true},
+		{doc: `shift: {hour: 3}`, errorExpected: true},
+		{doc: `shift: Y1 Y2`, errorExpected: true},
+		{doc: `shift: {years: "1"}`, errorExpected: true},
+		{doc: `shift: {year: ["+1"]}`, errorExpected: true},
+		{doc: `shift: [Y+1]`, errorExpected: true},
+	}
+
+	for i, p := range list {
+		var v struct {
+			Shift TimeShift `yaml:"shift"`
+		}
+
+		err := yaml.Unmarshal([]byte(p.doc), &v)
+		if err != nil {
+			if !p.errorExpected {
+				t.Errorf(`[%d] %s: %s`, i, p.doc, err)
+			}
+			continue
+		}
+
+		if p.errorExpected {
+			t.Errorf(`[%d] %s: error expected`, i, p.doc)
+			continue
+		}
+
+		if r := v.Shift.Exec(src); r != p.result {
+			t.Errorf(`[%d] %s: got "%s", expected "%s"`, i, p.doc, r, p.result)
+		}
+	}
+}
+
+//----------------------------------------------------------------------------------------------------------------------------//